@@ -0,0 +1,137 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"analytics/models"
+)
+
+// Count is a name with its number of missing events
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Payload is the JSON alert body
+type Payload struct {
+	Timestamp      string      `json:"timestamp"`
+	ReportPath     string      `json:"report_path,omitempty"`
+	TotalMissing   int         `json:"total_missing"`
+	Errors         int         `json:"errors"`
+	Triggered      []Triggered `json:"triggered"`
+	TopCollections []Count     `json:"top_collections"`
+	TopEventNames  []Count     `json:"top_event_names"`
+}
+
+// NewPayload builds the alert body for the triggered rules
+func (c *Config) NewPayload(report models.MissingDataReport, reportPath string, triggered []Triggered) Payload {
+	collections := make(map[string]int)
+	eventNames := make(map[string]int)
+	for collection, missingEvents := range report.ByCollection {
+		collections[collection] += len(missingEvents)
+		for _, missingEvent := range missingEvents {
+			eventNames[missingEvent.EventName]++
+		}
+	}
+
+	return Payload{
+		Timestamp:      report.Timestamp,
+		ReportPath:     reportPath,
+		TotalMissing:   report.TotalCount,
+		Errors:         len(report.Errors),
+		Triggered:      triggered,
+		TopCollections: topCounts(collections, c.TopN),
+		TopEventNames:  topCounts(eventNames, c.TopN),
+	}
+}
+
+// Send posts the payload to the webhook in the configured format
+func (c *Config) Send(payload Payload) error {
+	var body interface{} = payload
+	if c.Format == "slack" {
+		body = map[string]string{"text": slackText(payload)}
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %v", err)
+	}
+
+	client := &http.Client{Timeout: time.Duration(c.TimeoutSeconds) * time.Second}
+	resp, err := client.Post(c.WebhookURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Print writes the payload to standard output, used when alerts are tested without sending
+func (c *Config) Print(payload Payload) {
+	if c.Format == "slack" {
+		fmt.Print(slackText(payload))
+		return
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(payload)
+}
+
+// slackText renders the payload as a Slack message
+func slackText(payload Payload) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, ":rotating_light: *Event recovery validation alert* (%d missing, %d errors)\n", payload.TotalMissing, payload.Errors)
+	for _, triggered := range payload.Triggered {
+		fmt.Fprintf(&text, "• *%s*: %s\n", triggered.Rule, triggered.Message)
+	}
+	if len(payload.TopCollections) > 0 {
+		fmt.Fprintf(&text, "Top collections: %s\n", joinCounts(payload.TopCollections))
+	}
+	if len(payload.TopEventNames) > 0 {
+		fmt.Fprintf(&text, "Top event names: %s\n", joinCounts(payload.TopEventNames))
+	}
+	if payload.ReportPath != "" {
+		fmt.Fprintf(&text, "Report: `%s`\n", payload.ReportPath)
+	}
+	return text.String()
+}
+
+// joinCounts formats counts as "name (n), ..."
+func joinCounts(counts []Count) string {
+	parts := make([]string, 0, len(counts))
+	for _, count := range counts {
+		parts = append(parts, fmt.Sprintf("%s (%d)", count.Name, count.Count))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// topCounts returns the n largest counts, ties broken by name
+func topCounts(counts map[string]int, n int) []Count {
+	sorted := make([]Count, 0, len(counts))
+	for name, count := range counts {
+		sorted = append(sorted, Count{Name: name, Count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
@@ -0,0 +1,166 @@
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"analytics/models"
+)
+
+// Rule types
+const (
+	RuleMissingTotal    = "missing_total"    // Total missing events above Threshold
+	RuleMissingRatio    = "missing_ratio"    // Missing percentage of Collection (any collection if empty) above Threshold
+	RuleErrors          = "errors"           // Errors containing Match (any error if empty) above Threshold
+	RuleMissingIncrease = "missing_increase" // Missing events grew by more than Threshold since the previous run
+)
+
+// Config defines the alert webhook and the rules that trigger it
+type Config struct {
+	WebhookURL     string  `json:"webhook_url"`     // Environment variables are expanded, e.g. ${ALERT_WEBHOOK}
+	Format         string  `json:"format"`          // Payload format: "json" (default) or "slack"
+	TopN           int     `json:"top_n"`           // Number of top collections and event names in the payload
+	TimeoutSeconds int     `json:"timeout_seconds"` // Webhook request timeout
+	Rules          []*Rule `json:"rules"`
+}
+
+// Rule is a single alert condition
+type Rule struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	Threshold  float64 `json:"threshold"`
+	Collection string  `json:"collection"` // missing_ratio only
+	Match      string  `json:"match"`      // errors only, e.g. "MySQL" or "context deadline exceeded"
+}
+
+// Baseline holds the counts of the previous run compared by missing_increase rules
+type Baseline struct {
+	Source  string // Where the baseline came from, e.g. a run ID or report file
+	Missing int
+}
+
+// Triggered is a rule whose condition was met
+type Triggered struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// LoadConfig reads the alert configuration from a JSON file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading alert rules: %v", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing alert rules: %v", err)
+	}
+
+	config.WebhookURL = os.ExpandEnv(config.WebhookURL)
+	if config.Format == "" {
+		config.Format = "json"
+	}
+	if config.Format != "json" && config.Format != "slack" {
+		return nil, fmt.Errorf("unsupported alert format %q", config.Format)
+	}
+	if config.TopN <= 0 {
+		config.TopN = 5
+	}
+	if config.TimeoutSeconds <= 0 {
+		config.TimeoutSeconds = 10
+	}
+
+	for i, rule := range config.Rules {
+		switch rule.Type {
+		case RuleMissingTotal, RuleMissingRatio, RuleErrors, RuleMissingIncrease:
+		default:
+			return nil, fmt.Errorf("alert rule %d: unknown type %q", i+1, rule.Type)
+		}
+		if rule.Name == "" {
+			rule.Name = rule.Type
+		}
+	}
+	return &config, nil
+}
+
+// Evaluate returns the rules triggered by the report. previous may be nil, in
+// which case missing_increase rules are skipped.
+func (c *Config) Evaluate(report models.MissingDataReport, previous *Baseline) []Triggered {
+	var triggered []Triggered
+	for _, rule := range c.Rules {
+		if message, ok := rule.evaluate(report, previous); ok {
+			triggered = append(triggered, Triggered{Rule: rule.Name, Message: message})
+		}
+	}
+	return triggered
+}
+
+// evaluate checks a single rule and describes why it fired
+func (r *Rule) evaluate(report models.MissingDataReport, previous *Baseline) (string, bool) {
+	switch r.Type {
+	case RuleMissingTotal:
+		if float64(report.TotalCount) > r.Threshold {
+			return fmt.Sprintf("%d events missing (threshold %g)", report.TotalCount, r.Threshold), true
+		}
+
+	case RuleMissingRatio:
+		// Reports written before checked counts were recorded cannot be evaluated
+		var over []string
+		for _, collection := range sortedCollections(report.CheckedCount) {
+			if r.Collection != "" && collection != r.Collection {
+				continue
+			}
+			checked := report.CheckedCount[collection]
+			if checked == 0 {
+				continue
+			}
+			missing := len(report.ByCollection[collection])
+			ratio := float64(missing) / float64(checked) * 100
+			if ratio > r.Threshold {
+				over = append(over, fmt.Sprintf("%s %.1f%% (%d of %d)", collection, ratio, missing, checked))
+			}
+		}
+		if len(over) > 0 {
+			return fmt.Sprintf("missing ratio above %g%%: %s", r.Threshold, strings.Join(over, ", ")), true
+		}
+
+	case RuleErrors:
+		count := 0
+		for _, errMsg := range report.Errors {
+			if strings.Contains(errMsg, r.Match) {
+				count++
+			}
+		}
+		if float64(count) > r.Threshold {
+			if r.Match != "" {
+				return fmt.Sprintf("%d errors matching %q (threshold %g)", count, r.Match, r.Threshold), true
+			}
+			return fmt.Sprintf("%d errors (threshold %g)", count, r.Threshold), true
+		}
+
+	case RuleMissingIncrease:
+		if previous == nil {
+			return "", false
+		}
+		increase := report.TotalCount - previous.Missing
+		if float64(increase) > r.Threshold {
+			return fmt.Sprintf("missing events rose from %d to %d since %s (threshold %g)",
+				previous.Missing, report.TotalCount, previous.Source, r.Threshold), true
+		}
+	}
+	return "", false
+}
+
+// sortedCollections returns the collection names in a stable order
+func sortedCollections(counts map[string]int) []string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"analytics/alert"
+	"analytics/config"
+	"analytics/db"
+	"analytics/models"
+	"analytics/report"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// runAlerts evaluates the alert rules against a saved report, so rules can be tried without a validation run
+func runAlerts(cfg *config.Configuration) {
+	missingReport, err := report.LoadReport(cfg.ReportFile)
+	if err != nil {
+		log.Fatalf("Failed to load report: %v", err)
+	}
+
+	var previous *alert.Baseline
+	if cfg.PreviousReportFile != "" {
+		previousReport, err := report.LoadReport(cfg.PreviousReportFile)
+		if err != nil {
+			log.Fatalf("Failed to load previous report: %v", err)
+		}
+		previous = &alert.Baseline{Source: cfg.PreviousReportFile, Missing: previousReport.TotalCount}
+	}
+
+	if !evaluateAlerts(cfg, missingReport, cfg.ReportFile, previous) {
+		fmt.Println("No alert rules triggered")
+	}
+}
+
+// sendRunAlerts evaluates the alert rules after a validation run, comparing with the previous stored run if there is one
+func sendRunAlerts(database *mongo.Database, cfg *config.Configuration, summary models.RunSummary, missingReport models.MissingDataReport) {
+	var previous *alert.Baseline
+	if cfg.HasSink("mongo") {
+		previousSummary, err := db.PreviousRunSummary(database, cfg.RunsCollection, summary.RunID, cfg.QueryTimeout)
+		if err != nil {
+			fmt.Printf("⚠️ Could not load the previous run for alerts: %v\n", err)
+		} else if previousSummary != nil {
+			previous = &alert.Baseline{Source: "run " + previousSummary.RunID, Missing: previousSummary.Missing}
+		}
+	}
+
+	evaluateAlerts(cfg, missingReport, summary.ReportPath, previous)
+}
+
+// evaluateAlerts sends or prints the alert for the triggered rules and reports whether any rule triggered
+func evaluateAlerts(cfg *config.Configuration, missingReport models.MissingDataReport, reportPath string, previous *alert.Baseline) bool {
+	triggered := cfg.Alerts.Evaluate(missingReport, previous)
+	if len(triggered) == 0 {
+		return false
+	}
+
+	for _, rule := range triggered {
+		fmt.Printf("⚠️ Alert %s: %s\n", rule.Rule, rule.Message)
+	}
+
+	payload := cfg.Alerts.NewPayload(missingReport, reportPath, triggered)
+	if cfg.DryRun {
+		fmt.Println("Dry run, alert not sent:")
+		cfg.Alerts.Print(payload)
+		return true
+	}
+
+	if err := cfg.Alerts.Send(payload); err != nil {
+		fmt.Printf("❌ Failed to send alert: %v\n", err)
+	} else {
+		fmt.Printf("✅ Sent alert for %d rules\n", len(triggered))
+	}
+	return true
+}
@@ -0,0 +1,253 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Archiver stores a batch of source documents and verifies the copy before returning.
+// A nil error means every document in the batch can safely be deleted from the source.
+type Archiver interface {
+	Archive(docs []bson.Raw) error
+	Describe() string
+}
+
+// CollectionArchiver copies documents into an archive collection
+type CollectionArchiver struct {
+	collection *mongo.Collection
+	timeoutSec int
+}
+
+// NewCollectionArchiver creates an archiver writing to the given collection
+func NewCollectionArchiver(db *mongo.Database, collectionName string, timeoutSec int) *CollectionArchiver {
+	return &CollectionArchiver{collection: db.Collection(collectionName), timeoutSec: timeoutSec}
+}
+
+// Describe implements Archiver
+func (a *CollectionArchiver) Describe() string {
+	return "collection " + a.collection.Name()
+}
+
+// Archive implements Archiver
+func (a *CollectionArchiver) Archive(docs []bson.Raw) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(a.timeoutSec)*time.Second)
+	defer cancel()
+
+	ids := make(bson.A, 0, len(docs))
+	inserts := make([]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.Lookup("_id"))
+		inserts = append(inserts, doc)
+	}
+
+	// Documents left over from an interrupted cleanup already exist, verification below covers them
+	_, err := a.collection.InsertMany(ctx, inserts, options.InsertMany().SetOrdered(false))
+	if err != nil && !onlyDuplicateKeyErrors(err) {
+		return fmt.Errorf("failed to write archive: %v", err)
+	}
+
+	// Read the copies back and compare them byte for byte
+	cursor, err := a.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return fmt.Errorf("failed to read archive back: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	archived := make(map[string]bson.Raw)
+	for cursor.Next(ctx) {
+		archived[cursor.Current.Lookup("_id").String()] = append(bson.Raw(nil), cursor.Current...)
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to read archive back: %v", err)
+	}
+
+	return verifyDocs(docs, archived)
+}
+
+// onlyDuplicateKeyErrors reports whether every write error is a duplicate key error
+func onlyDuplicateKeyErrors(err error) bool {
+	bulkErr, ok := err.(mongo.BulkWriteException)
+	if !ok || bulkErr.WriteConcernError != nil {
+		return false
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Code != 11000 {
+			return false
+		}
+	}
+	return true
+}
+
+// Archive file formats
+const (
+	FormatBSON = "bson"
+	FormatJSON = "json"
+)
+
+// FileArchiver writes each batch to a gzip-compressed local file
+type FileArchiver struct {
+	dir    string
+	prefix string
+	format string
+	batch  int
+}
+
+// NewFileArchiver creates an archiver writing files named after the source collection into dir
+func NewFileArchiver(dir string, collectionName string, format string) (*FileArchiver, error) {
+	if format != FormatBSON && format != FormatJSON {
+		return nil, fmt.Errorf("unknown archive format %q", format)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %v", err)
+	}
+
+	prefix := fmt.Sprintf("%s_%s", collectionName, time.Now().Format("20060102_150405"))
+	return &FileArchiver{dir: dir, prefix: prefix, format: format}, nil
+}
+
+// Describe implements Archiver
+func (a *FileArchiver) Describe() string {
+	return fmt.Sprintf("%s files in %s", a.format, a.dir)
+}
+
+// Archive implements Archiver
+func (a *FileArchiver) Archive(docs []bson.Raw) error {
+	a.batch++
+	extension := ".bson.gz"
+	if a.format == FormatJSON {
+		extension = ".jsonl.gz"
+	}
+	filename := filepath.Join(a.dir, fmt.Sprintf("%s_%04d%s", a.prefix, a.batch, extension))
+
+	if err := a.writeFile(filename, docs); err != nil {
+		return fmt.Errorf("failed to write %s: %v", filename, err)
+	}
+
+	// Read the file back and compare it with the source documents
+	archived, err := a.readFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read %s back: %v", filename, err)
+	}
+	if err := verifyDocs(docs, archived); err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+
+	fmt.Printf("Archived %d documents to %s\n", len(docs), filename)
+	return nil
+}
+
+// writeFile writes the documents as concatenated BSON or as canonical extended JSON lines
+func (a *FileArchiver) writeFile(filename string, docs []bson.Raw) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	for _, doc := range docs {
+		data := []byte(doc)
+		if a.format == FormatJSON {
+			data, err = bson.MarshalExtJSON(doc, true, false)
+			if err != nil {
+				return err
+			}
+			data = append(data, '\n')
+		}
+		if _, err := gz.Write(data); err != nil {
+			return err
+		}
+	}
+
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// readFile decodes an archive file into documents keyed by _id
+func (a *FileArchiver) readFile(filename string) (map[string]bson.Raw, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, err
+	}
+
+	archived := make(map[string]bson.Raw)
+	if a.format == FormatJSON {
+		for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+			var doc bson.Raw
+			if err := bson.UnmarshalExtJSON(line, true, &doc); err != nil {
+				return nil, err
+			}
+			archived[doc.Lookup("_id").String()] = doc
+		}
+		return archived, nil
+	}
+
+	for len(data) > 0 {
+		length, ok := bsonLength(data)
+		if !ok {
+			return nil, fmt.Errorf("truncated BSON document")
+		}
+		doc := bson.Raw(data[:length])
+		if err := doc.Validate(); err != nil {
+			return nil, err
+		}
+		archived[doc.Lookup("_id").String()] = doc
+		data = data[length:]
+	}
+	return archived, nil
+}
+
+// bsonLength reads the little-endian length prefix of the next BSON document
+func bsonLength(data []byte) (int, bool) {
+	if len(data) < 5 {
+		return 0, false
+	}
+	length := int(binary.LittleEndian.Uint32(data))
+	return length, length >= 5 && length <= len(data)
+}
+
+// verifyDocs checks that every source document has an identical archived copy.
+// Documents are compared field by field so that extended JSON round trips are accepted.
+func verifyDocs(docs []bson.Raw, archived map[string]bson.Raw) error {
+	for _, doc := range docs {
+		id := doc.Lookup("_id").String()
+		archivedDoc, ok := archived[id]
+		if !ok {
+			return fmt.Errorf("document %s missing from archive", id)
+		}
+		if !bytes.Equal(doc, archivedDoc) && !sameDocument(doc, archivedDoc) {
+			return fmt.Errorf("archived copy of document %s differs from the source", id)
+		}
+	}
+	return nil
+}
+
+// sameDocument compares two documents by their canonical extended JSON form
+func sameDocument(a, b bson.Raw) bool {
+	aJSON, errA := bson.MarshalExtJSON(a, true, false)
+	bJSON, errB := bson.MarshalExtJSON(b, true, false)
+	return errA == nil && errB == nil && bytes.Equal(aJSON, bJSON)
+}
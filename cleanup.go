@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"analytics/archive"
+	"analytics/config"
+	"analytics/db"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// runCleanup moves fully recovered documents out of the source collection. Each batch
+// is archived and verified before it is deleted from the source.
+func runCleanup(database *mongo.Database, cfg *config.Configuration) {
+	var archiver archive.Archiver
+	if cfg.ArchiveDir != "" {
+		fileArchiver, err := archive.NewFileArchiver(cfg.ArchiveDir, cfg.CollectionName, cfg.ArchiveFormat)
+		if err != nil {
+			log.Fatalf("Failed to set up file archive: %v", err)
+		}
+		archiver = fileArchiver
+	} else {
+		archiver = archive.NewCollectionArchiver(database, cfg.ArchiveCollection, cfg.QueryTimeout)
+	}
+
+	fmt.Printf("Cleaning up fully recovered documents older than %s from %s into %s\n",
+		cfg.MinAge, cfg.CollectionName, archiver.Describe())
+	if cfg.DryRun {
+		fmt.Println("Dry run: nothing will be archived or deleted")
+	}
+
+	ctx := context.Background()
+	cursor, err := db.FindArchivable(ctx, database, cfg.CollectionName, cfg.MinAge, cfg.DocLimit)
+	if err != nil {
+		log.Fatalf("Failed to query archivable documents: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var matched, archived, deleted, failed int64
+	var batch []bson.Raw
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		defer func() { batch = nil }()
+
+		if cfg.DryRun {
+			return
+		}
+
+		if err := archiver.Archive(batch); err != nil {
+			fmt.Printf("❌ Failed to archive batch of %d documents, keeping them in the source: %v\n", len(batch), err)
+			failed += int64(len(batch))
+			return
+		}
+		archived += int64(len(batch))
+
+		ids := make(bson.A, 0, len(batch))
+		for _, doc := range batch {
+			ids = append(ids, doc.Lookup("_id"))
+		}
+		count, err := db.DeleteArchived(database, cfg.CollectionName, ids, cfg.MinAge, cfg.QueryTimeout)
+		if err != nil {
+			fmt.Printf("❌ Failed to delete %d archived documents from the source: %v\n", len(batch), err)
+			return
+		}
+		deleted += count
+	}
+
+	var events int
+	for cursor.Next(ctx) {
+		doc := append(bson.Raw(nil), cursor.Current...)
+		matched++
+		if eventArray, ok := doc.Lookup("event").ArrayOK(); ok {
+			values, _ := eventArray.Values()
+			events += len(values)
+		}
+
+		batch = append(batch, doc)
+		if len(batch) >= cfg.CleanupBatch {
+			flush()
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		flush()
+		log.Fatalf("Failed to read archivable documents: %v", err)
+	}
+	flush()
+
+	if cfg.DryRun {
+		fmt.Printf("Dry run: %d documents with %d events would be archived and deleted\n", matched, events)
+		return
+	}
+
+	fmt.Printf("Cleanup summary: %d documents matched, %d archived, %d deleted, %d failed\n", matched, archived, deleted, failed)
+}
@@ -2,14 +2,56 @@ package config
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+
+	"analytics/alert"
+	"analytics/db"
+	"analytics/extract"
+	"analytics/models"
+)
+
+// DefaultCompareFields lists the field pairs checked in deep-compare mode
+const DefaultCompareFields = "event_name=event.eventName,session_id=event.sessionId,uuid=event.uuid,entity_code=event.entityCode"
+
+// Commands selected by the first command-line argument
+const (
+	CommandValidate  = "validate"
+	CommandPreflight = "preflight"
+	CommandCleanup   = "cleanup"
+	CommandAlerts    = "alerts"
+	CommandSchedule  = "schedule"
+	CommandWatch     = "watch"
+	CommandRecheck   = "recheck"
 )
 
+// commands lists every known command
+var commands = map[string]bool{
+	CommandValidate:  true,
+	CommandPreflight: true,
+	CommandCleanup:   true,
+	CommandAlerts:    true,
+	CommandSchedule:  true,
+	CommandWatch:     true,
+	CommandRecheck:   true,
+}
+
+// sinkNames lists every known result sink
+var sinkNames = map[string]bool{
+	"file":    true,
+	"stdout":  true,
+	"mongo":   true,
+	"webhook": true,
+}
+
 // Configuration holds all the configurable parameters
 type Configuration struct {
+	Command           string
 	MongoURI          string
 	DatabaseName      string
 	DocLimit          int
@@ -17,6 +59,96 @@ type Configuration struct {
 	QueryTimeout      int
 	ConnectionTimeout int
 	MaxConcurrent     int
+
+	// MongoDB connection options
+	TLSCAFile       string
+	TLSCertFile     string
+	TLSKeyFile      string
+	AuthMechanism   string
+	AuthSource      string
+	ReadPreference  string
+	MaxStaleness    time.Duration
+	ReadConcern     string
+	Compressors     []string
+	MinPoolSize     uint64
+	MaxPoolSize     uint64
+	MaxConnIdleTime time.Duration
+
+	IndexCheck      string
+	CreateIndexes   bool
+	PreflightSample int
+	WriteBack       bool
+	SkipVerified    bool
+
+	// Result sinks, see the sink package
+	Sinks          []string
+	ReportDir      string
+	WebhookURL     string
+	WebhookTimeout time.Duration
+
+	// Delays after the first pass at which missing events are looked up again
+	RecheckDelays []time.Duration
+
+	// Sliding window and recurring runs of the schedule command
+	Since           time.Duration
+	Cron            string
+	ScheduleHistory int
+	StatusAddr      string
+
+	// Change stream validation of the watch command
+	SettleDelay      time.Duration
+	ResumeCollection string
+
+	// Exit status thresholds and the machine-readable summary
+	MaxMissing  int
+	MaxErrors   int
+	SummaryJSON string
+
+	// Threshold alerts loaded from AlertRulesFile
+	AlertRulesFile     string
+	Alerts             *alert.Config
+	ReportFile         string
+	PreviousReportFile string
+
+	// Run history stored in MongoDB by the mongo sink
+	PersistResults     bool
+	RunsCollection     string
+	FindingsCollection string
+
+	// Cleanup of fully recovered documents
+	ArchiveCollection string
+	ArchiveDir        string
+	ArchiveFormat     string
+	MinAge            time.Duration
+	CleanupBatch      int
+	DryRun            bool
+
+	DeepCompare       bool
+	CompareFields     string
+	FieldPairs        []models.FieldPair
+	TopUsers          int
+	PseudonymiseUUIDs bool
+	UUIDSalt          string
+	PlatformSeparator string
+	PlatformRegex     string
+	Platforms         extract.PlatformExtractor
+	EventTimeSource   string
+	EventTime         extract.TimeExtractor
+
+	// MySQL verification, disabled when MySQLDSN is empty
+	MySQLDSN             string
+	MySQLMaxOpenConns    int
+	MySQLMaxIdleConns    int
+	MySQLConnMaxLifetime time.Duration
+	MySQLQueryTimeout    int
+	MySQLMaxConcurrent   int
+	MySQLBatchSize       int
+	MySQLScreens         db.EventNameMap
+	MySQLTimeWindow      time.Duration
+
+	// Extra MySQL destination checks loaded from SQLChecksFile
+	SQLChecksFile string
+	SQLChecks     []*db.SQLCheck
 }
 
 // ParseFlags parses command-line flags and returns a Configuration
@@ -30,6 +162,8 @@ func ParseFlags() *Configuration {
 
 	mongo_url := os.Getenv("MONGO_DB_URL")
 	db_name := os.Getenv("DATABASE_NAME")
+	uuid_salt := os.Getenv("UUID_SALT")
+	mysql_dsn := os.Getenv("MYSQL_DSN")
 
 	flag.StringVar(&config.MongoURI, "mongo-uri", mongo_url, "MongoDB connection URI")
 	flag.StringVar(&config.DatabaseName, "db", db_name, "MongoDB database name")
@@ -38,9 +172,285 @@ func ParseFlags() *Configuration {
 	flag.IntVar(&config.QueryTimeout, "query-timeout", 15, "Query timeout in seconds")
 	flag.IntVar(&config.ConnectionTimeout, "conn-timeout", 30, "Connection timeout in seconds")
 	flag.IntVar(&config.MaxConcurrent, "max-concurrent", 10, "Maximum number of concurrent operations")
+	flag.StringVar(&config.TLSCAFile, "tls-ca-file", "", "CA bundle used to verify the MongoDB server certificate")
+	flag.StringVar(&config.TLSCertFile, "tls-cert-file", "", "Client certificate (PEM) for MongoDB TLS")
+	flag.StringVar(&config.TLSKeyFile, "tls-key-file", "", "Private key of the client certificate (default: read from -tls-cert-file)")
+	flag.StringVar(&config.AuthMechanism, "auth-mechanism", "", "MongoDB auth mechanism, e.g. SCRAM-SHA-256 or MONGODB-X509 (default: from URI)")
+	flag.StringVar(&config.AuthSource, "auth-source", "", "MongoDB auth database (default: from URI)")
+	flag.StringVar(&config.ReadPreference, "read-preference", "", "Read preference for all reads, e.g. secondaryPreferred (default: from URI)")
+	flag.DurationVar(&config.MaxStaleness, "max-staleness", 0, "Maximum replication lag of secondaries used for reads (minimum 90s, 0 = no limit)")
+	flag.StringVar(&config.ReadConcern, "read-concern", "", "Read concern level, e.g. local or majority (default: from URI)")
+	compressors := flag.String("compressors", "", "Comma-separated wire compressors, e.g. zstd,snappy,zlib")
+	flag.Uint64Var(&config.MinPoolSize, "min-pool-size", 10, "Minimum MongoDB connection pool size")
+	flag.Uint64Var(&config.MaxPoolSize, "max-pool-size", 100, "Maximum MongoDB connection pool size")
+	flag.DurationVar(&config.MaxConnIdleTime, "max-conn-idle", time.Minute, "Close MongoDB connections idle for longer than this")
+
+	flag.StringVar(&config.IndexCheck, "index-check", "warn", "Check destination indexes before validating: off, warn or strict (refuse to run on COLLSCAN)")
+	flag.BoolVar(&config.CreateIndexes, "create-indexes", false, "Create missing event.mappingId indexes on destination collections")
+
+	flag.BoolVar(&config.DeepCompare, "deep-compare", false, "Fetch matched destination documents and compare field values")
+	flag.StringVar(&config.CompareFields, "compare-fields", DefaultCompareFields, "Comma-separated source=destination field pairs used by -deep-compare")
+
+	flag.IntVar(&config.TopUsers, "top-users", 10, "Number of most affected users listed in the report")
+	flag.BoolVar(&config.PseudonymiseUUIDs, "pseudonymise-uuids", false, "Replace user UUIDs with a keyed hash in the report")
+	flag.StringVar(&config.UUIDSalt, "uuid-salt", uuid_salt, "Key used to pseudonymise UUIDs")
+
+	flag.StringVar(&config.PlatformSeparator, "platform-separator", "-", "Separator after the platform prefix in session IDs")
+	flag.StringVar(&config.PlatformRegex, "platform-regex", "", "Regex with a capture group extracting the platform from session IDs (overrides -platform-separator)")
+	flag.StringVar(&config.EventTimeSource, "event-time", "id-prefix", "How event times are derived for the timeline (id-prefix, none)")
+
+	flag.StringVar(&config.MySQLDSN, "mysql-dsn", mysql_dsn, "MySQL DSN for app_tracking_new checks (empty = skip MySQL)")
+	flag.IntVar(&config.MySQLMaxOpenConns, "mysql-max-open", 10, "Maximum open MySQL connections")
+	flag.IntVar(&config.MySQLMaxIdleConns, "mysql-max-idle", 5, "Maximum idle MySQL connections")
+	flag.DurationVar(&config.MySQLConnMaxLifetime, "mysql-conn-lifetime", 3*time.Minute, "Maximum lifetime of a MySQL connection")
+	flag.IntVar(&config.MySQLQueryTimeout, "mysql-query-timeout", 15, "MySQL query timeout in seconds")
+	flag.IntVar(&config.MySQLMaxConcurrent, "mysql-max-concurrent", 4, "Maximum number of concurrent MySQL queries")
+	flag.IntVar(&config.MySQLBatchSize, "mysql-batch-size", 200, "Maximum number of session IDs per MySQL query")
+	flag.DurationVar(&config.MySQLTimeWindow, "mysql-time-window", 6*time.Hour, "Tolerance between the event time and the matched MySQL session (0 = newest row wins)")
+	flag.StringVar(&config.SQLChecksFile, "sql-checks", "", "JSON file defining extra MySQL destination checks")
+	screens := flag.String("mysql-screens", "", "Comma-separated EVENT_NAME.column=screen matches replacing the defaults of the events they name, e.g. DETAIL_EXIT.current_screen=SERIES_DETAIL")
+
+	flag.BoolVar(&config.WriteBack, "write-back", false, "Write the validation status onto each processed recovery document")
+	flag.BoolVar(&config.SkipVerified, "skip-verified", false, "Skip recovery documents whose last written-back validation found every event")
+	sinks := flag.String("sinks", "file", "Comma-separated result sinks: file, stdout, mongo, webhook")
+	flag.StringVar(&config.ReportDir, "report-dir", "missing_data", "Directory the file sink writes reports into")
+	flag.StringVar(&config.WebhookURL, "webhook-url", "", "URL the webhook sink posts findings and the run summary to")
+	flag.DurationVar(&config.WebhookTimeout, "webhook-timeout", 10*time.Second, "Timeout of each webhook request")
+	recheckDelays := flag.String("recheck-delays", "", "Comma-separated delays after the first pass at which missing events are re-checked, e.g. 1m,5m,30m (empty = disabled)")
+	flag.DurationVar(&config.Since, "since", 0, "Only validate documents recovered within this long before the run (0 = all documents)")
+	flag.StringVar(&config.Cron, "cron", "", "Cron expression of the schedule command, e.g. \"*/15 * * * *\" or \"@hourly\"")
+	flag.IntVar(&config.ScheduleHistory, "schedule-history", 50, "Number of run summaries the schedule command keeps")
+	flag.StringVar(&config.StatusAddr, "status-addr", "", "Serve the schedule history as JSON on this address, e.g. :8080 (empty = disabled)")
+	flag.DurationVar(&config.SettleDelay, "settle-delay", time.Minute, "How long the watch command waits after a document is inserted before validating it")
+	flag.StringVar(&config.ResumeCollection, "resume-collection", "validation_resume_tokens", "Collection storing the watch command's change stream position")
+	flag.IntVar(&config.MaxMissing, "max-missing", 0, "Exit with code 3 when more events than this are missing (-1 = never)")
+	flag.IntVar(&config.MaxErrors, "max-errors", 0, "Exit with code 4 when more errors than this occur (-1 = never)")
+	flag.StringVar(&config.SummaryJSON, "summary-json", "", "Write the final run summary as one JSON object to this file (- = stdout)")
+	flag.StringVar(&config.AlertRulesFile, "alert-rules", "", "JSON file defining threshold alerts and their webhook")
+	flag.StringVar(&config.ReportFile, "report", "", "Saved report file read by the alerts and recheck commands")
+	flag.StringVar(&config.PreviousReportFile, "previous-report", "", "Saved report the alerts command compares against for missing_increase rules")
+	flag.BoolVar(&config.PersistResults, "persist-results", false, "Store the run summary and findings in MongoDB (same as adding the mongo sink)")
+	flag.StringVar(&config.RunsCollection, "runs-collection", "validation_runs", "Collection storing run summaries")
+	flag.StringVar(&config.FindingsCollection, "findings-collection", "validation_findings", "Collection storing missing, duplicate and error findings")
+	flag.StringVar(&config.ArchiveCollection, "archive-collection", "new_event_recovery_archive", "Collection that cleanup moves fully recovered documents into")
+	flag.StringVar(&config.ArchiveDir, "archive-dir", "", "Archive into compressed local files in this directory instead of a collection")
+	flag.StringVar(&config.ArchiveFormat, "archive-format", "bson", "Format of archive files: bson or json")
+	flag.DurationVar(&config.MinAge, "min-age", 7*24*time.Hour, "Only clean up documents older than this")
+	flag.IntVar(&config.CleanupBatch, "cleanup-batch", 100, "Number of documents archived and deleted per batch")
+	flag.BoolVar(&config.DryRun, "dry-run", false, "Report what cleanup would do without archiving or deleting anything, or print alerts instead of sending them")
+	flag.IntVar(&config.PreflightSample, "preflight-sample", 100, "Number of source documents decoded by the preflight command")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [validate|preflight|cleanup|alerts|schedule|watch|recheck] [flags]\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprintf(flag.CommandLine.Output(), `
+Exit codes:
+  0  clean run within -max-missing and -max-errors
+  1  failure, e.g. invalid configuration, a database could not be reached or a result sink failed
+  2  flags that could not be parsed
+  3  more missing events than -max-missing
+  4  more errors than -max-errors (takes precedence over 3)
+`)
+	}
+
+	// The command comes first, flags follow it
+	config.Command = CommandValidate
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		config.Command = args[0]
+		args = args[1:]
+	}
+	if !commands[config.Command] {
+		flag.Usage()
+		log.Fatalf("Unknown command: %s", config.Command)
+	}
 
 	// Parse command-line flags
-	flag.Parse()
+	flag.CommandLine.Parse(args)
+
+	if config.IndexCheck != "off" && config.IndexCheck != "warn" && config.IndexCheck != "strict" {
+		log.Fatalf("Invalid -index-check: %q (expected off, warn or strict)", config.IndexCheck)
+	}
+
+	if config.CleanupBatch < 1 {
+		log.Fatal("-cleanup-batch must be at least 1")
+	}
+
+	if config.MySQLMaxConcurrent < 1 || config.MySQLBatchSize < 1 {
+		log.Fatal("-mysql-max-concurrent and -mysql-batch-size must be at least 1")
+	}
+
+	if config.PseudonymiseUUIDs && config.UUIDSalt == "" {
+		log.Fatal("-pseudonymise-uuids requires -uuid-salt or UUID_SALT, an unkeyed hash can be reversed from a list of UUIDs")
+	}
+
+	config.Sinks = splitList(*sinks)
+	if config.PersistResults && !contains(config.Sinks, "mongo") {
+		config.Sinks = append(config.Sinks, "mongo")
+	}
+	for _, name := range config.Sinks {
+		if !sinkNames[name] {
+			log.Fatalf("Invalid -sinks: unknown sink %q (expected file, stdout, mongo or webhook)", name)
+		}
+	}
+	if config.HasSink("webhook") && config.WebhookURL == "" {
+		log.Fatal("-webhook-url is required by the webhook sink")
+	}
+
+	if config.AlertRulesFile != "" {
+		alerts, err := alert.LoadConfig(config.AlertRulesFile)
+		if err != nil {
+			log.Fatalf("Invalid -alert-rules: %v", err)
+		}
+		if alerts.WebhookURL == "" && !config.DryRun {
+			log.Fatal("-alert-rules must set webhook_url unless -dry-run is used")
+		}
+		config.Alerts = alerts
+	}
+	if config.Command == CommandAlerts && (config.Alerts == nil || config.ReportFile == "") {
+		log.Fatal("The alerts command requires -alert-rules and -report")
+	}
+
+	if config.Command == CommandRecheck && config.ReportFile == "" {
+		log.Fatal("The recheck command requires -report")
+	}
+	if config.Command == CommandSchedule {
+		if config.Cron == "" {
+			log.Fatal("The schedule command requires -cron")
+		}
+		if config.Since <= 0 {
+			log.Fatal("The schedule command requires -since, e.g. twice the interval between runs, so each run scans a sliding window")
+		}
+		if config.ScheduleHistory < 1 {
+			log.Fatal("-schedule-history must be at least 1")
+		}
+	}
+
+	for _, value := range splitList(*recheckDelays) {
+		delay, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid -recheck-delays: %v", err)
+		}
+		if n := len(config.RecheckDelays); n > 0 && delay <= config.RecheckDelays[n-1] {
+			log.Fatalf("Invalid -recheck-delays: %s must be longer than the delay before it", value)
+		}
+		config.RecheckDelays = append(config.RecheckDelays, delay)
+	}
+
+	config.Compressors = splitList(*compressors)
+	config.MySQLScreens = db.DefaultEventNameMap()
+	if *screens != "" {
+		overrides, err := parseScreenMatches(*screens)
+		if err != nil {
+			log.Fatalf("Invalid -mysql-screens: %v", err)
+		}
+		for eventName, match := range overrides {
+			config.MySQLScreens[eventName] = match
+		}
+	}
+
+	if config.SQLChecksFile != "" {
+		checks, err := db.LoadSQLChecks(config.SQLChecksFile)
+		if err != nil {
+			log.Fatalf("Invalid -sql-checks: %v", err)
+		}
+		config.SQLChecks = checks
+	}
+
+	platforms, err := extract.NewPlatformExtractor(config.PlatformSeparator, config.PlatformRegex)
+	if err != nil {
+		log.Fatalf("Invalid platform extractor: %v", err)
+	}
+	config.Platforms = platforms
+
+	eventTime, err := extract.NewTimeExtractor(config.EventTimeSource)
+	if err != nil {
+		log.Fatalf("Invalid -event-time: %v", err)
+	}
+	config.EventTime = eventTime
+
+	if config.DeepCompare {
+		pairs, err := parseFieldPairs(config.CompareFields)
+		if err != nil {
+			log.Fatalf("Invalid -compare-fields: %v", err)
+		}
+		config.FieldPairs = pairs
+	}
 
 	return config
 }
+
+// HasSink reports whether the named result sink is configured
+func (c *Configuration) HasSink(name string) bool {
+	return contains(c.Sinks, name)
+}
+
+// contains reports whether list holds value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseScreenMatches parses a list like "DETAIL_EXIT.current_screen=SERIES_DETAIL,DETAIL_EXIT.screen_type=series"
+func parseScreenMatches(value string) (db.EventNameMap, error) {
+	matches := make(db.EventNameMap)
+	for _, item := range splitList(value) {
+		key, screen, ok := strings.Cut(item, "=")
+		eventName, column, hasColumn := strings.Cut(strings.TrimSpace(key), ".")
+		screen = strings.TrimSpace(screen)
+		if !ok || !hasColumn || eventName == "" || screen == "" {
+			return nil, fmt.Errorf("expected EVENT_NAME.column=screen, got %q", item)
+		}
+		if err := db.ValidateMySQLColumns([]string{column}); err != nil {
+			return nil, err
+		}
+
+		if matches[eventName] == nil {
+			matches[eventName] = make(db.ScreenMatch)
+		}
+		matches[eventName][column] = screen
+	}
+	return matches, nil
+}
+
+// parseFieldPairs parses a list like "event_name=event.eventName,uuid=event.uuid"
+func parseFieldPairs(value string) ([]models.FieldPair, error) {
+	var pairs []models.FieldPair
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		source, dest, ok := strings.Cut(item, "=")
+		source, dest = strings.TrimSpace(source), strings.TrimSpace(dest)
+		if !ok || source == "" || dest == "" {
+			return nil, fmt.Errorf("expected source=destination, got %q", item)
+		}
+		if _, known := (models.Event{}).FieldValue(source); !known {
+			return nil, fmt.Errorf("unknown event field %q", source)
+		}
+
+		pairs = append(pairs, models.FieldPair{Source: source, Dest: dest})
+	}
+
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("no field pairs given")
+	}
+	return pairs, nil
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"flag"
+	"net/url"
+	"strings"
+)
+
+// secretFlags are flags whose values are never stored
+var secretFlags = map[string]bool{
+	"mysql-dsn":   true,
+	"uuid-salt":   true,
+	"webhook-url": true, // Webhook URLs such as Slack's carry their token in the path
+}
+
+// Snapshot returns the value of every flag with credentials redacted, for storing alongside run results
+func Snapshot() map[string]string {
+	snapshot := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		switch {
+		case secretFlags[f.Name] && value != "":
+			value = "[redacted]"
+		case strings.HasSuffix(f.Name, "-uri"):
+			value = redactURI(value)
+		}
+		snapshot[f.Name] = value
+	})
+	return snapshot
+}
+
+// redactURI removes the password from a connection URI
+func redactURI(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return "[redacted]"
+	}
+	if parsed.User != nil {
+		if _, hasPassword := parsed.User.Password(); hasPassword {
+			parsed.User = url.UserPassword(parsed.User.Username(), "redacted")
+		}
+	}
+	return parsed.String()
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"analytics/models"
+)
+
+// MappingIDField is the destination field every event lookup filters on
+const MappingIDField = "event.mappingId"
+
+// CheckDestinationIndexes lists the indexes on each destination collection and explains
+// a sample lookup to see whether it would scan the whole collection. With createMissing,
+// an index on event.mappingId is created on collections that lack one.
+// sampleIDs maps each collection to an event ID used for the explained lookup.
+func CheckDestinationIndexes(db *mongo.Database, sampleIDs map[string]string, timeoutSec int, createMissing bool) []models.IndexCheck {
+	var checks []models.IndexCheck
+
+	for collectionName, sampleID := range sampleIDs {
+		check := checkDestinationIndex(db, collectionName, sampleID, timeoutSec)
+
+		if createMissing && check.Error == nil && !check.HasIndex {
+			if err := createMappingIDIndex(db, collectionName, timeoutSec); err != nil {
+				check.Error = fmt.Errorf("failed to create index: %v", err)
+			} else {
+				check = checkDestinationIndex(db, collectionName, sampleID, timeoutSec)
+				check.Created = true
+			}
+		}
+
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// checkDestinationIndex inspects the indexes and query plan of a single collection
+func checkDestinationIndex(db *mongo.Database, collectionName string, sampleID string, timeoutSec int) models.IndexCheck {
+	check := models.IndexCheck{Collection: collectionName}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+	defer cancel()
+
+	// Look for an index whose first key is event.mappingId
+	cursor, err := db.Collection(collectionName).Indexes().List(ctx)
+	if err != nil {
+		check.Error = fmt.Errorf("failed to list indexes: %v", err)
+		return check
+	}
+
+	var indexes []struct {
+		Name string `bson:"name"`
+		Key  bson.D `bson:"key"`
+	}
+	if err := cursor.All(ctx, &indexes); err != nil {
+		check.Error = fmt.Errorf("failed to read indexes: %v", err)
+		return check
+	}
+
+	for _, index := range indexes {
+		check.Indexes = append(check.Indexes, index.Name)
+		// Only a leading key can serve an equality lookup on its own
+		if !check.HasIndex && len(index.Key) > 0 && index.Key[0].Key == MappingIDField {
+			check.HasIndex = true
+			check.IndexName = index.Name
+		}
+	}
+
+	// Explain a sample lookup to see the plan the server picks
+	command := bson.D{
+		{Key: "explain", Value: bson.D{
+			{Key: "find", Value: collectionName},
+			{Key: "filter", Value: bson.D{{Key: MappingIDField, Value: sampleID}}},
+		}},
+		{Key: "verbosity", Value: "queryPlanner"},
+	}
+
+	var explain bson.M
+	if err := db.RunCommand(ctx, command).Decode(&explain); err != nil {
+		check.Error = fmt.Errorf("failed to explain lookup: %v", err)
+		return check
+	}
+
+	planner, _ := explain["queryPlanner"].(bson.M)
+	check.PlanStages = planStages(planner["winningPlan"])
+	for _, stage := range check.PlanStages {
+		if stage == "COLLSCAN" {
+			check.CollectionScan = true
+		}
+	}
+
+	return check
+}
+
+// planStages collects every stage name in an explain plan, outermost first.
+// On sharded clusters the plan of every shard sits under shards[].winningPlan.
+func planStages(plan interface{}) []string {
+	var stages []string
+
+	switch node := plan.(type) {
+	case bson.M:
+		if stage, ok := node["stage"].(string); ok {
+			stages = append(stages, stage)
+		}
+		for _, key := range []string{"queryPlan", "inputStage", "inputStages", "shards", "winningPlan"} {
+			stages = append(stages, planStages(node[key])...)
+		}
+	case bson.A:
+		for _, child := range node {
+			stages = append(stages, planStages(child)...)
+		}
+	}
+
+	return stages
+}
+
+// createMappingIDIndex creates an ascending index on event.mappingId
+func createMappingIDIndex(db *mongo.Database, collectionName string, timeoutSec int) error {
+	// Index builds can take much longer than a single query
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second*20)
+	defer cancel()
+
+	indexModel := mongo.IndexModel{Keys: bson.D{{Key: MappingIDField, Value: 1}}}
+	name, err := db.Collection(collectionName).Indexes().CreateOne(ctx, indexModel)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created index %s on %s\n", name, collectionName)
+	return nil
+}
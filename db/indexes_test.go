@@ -0,0 +1,49 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPlanStages(t *testing.T) {
+	tests := []struct {
+		name string
+		plan interface{}
+		want []string
+	}{
+		{"nil plan", nil, nil},
+		{
+			"index scan",
+			bson.M{"stage": "FETCH", "inputStage": bson.M{"stage": "IXSCAN"}},
+			[]string{"FETCH", "IXSCAN"},
+		},
+		{
+			"slot based engine",
+			bson.M{"queryPlan": bson.M{"stage": "COLLSCAN"}},
+			[]string{"COLLSCAN"},
+		},
+		{
+			"or with input stages",
+			bson.M{"stage": "OR", "inputStages": bson.A{bson.M{"stage": "IXSCAN"}, bson.M{"stage": "COLLSCAN"}}},
+			[]string{"OR", "IXSCAN", "COLLSCAN"},
+		},
+		{
+			"sharded cluster",
+			bson.M{"stage": "SHARD_MERGE", "shards": bson.A{
+				bson.M{"shardName": "rs0", "winningPlan": bson.M{"stage": "FETCH", "inputStage": bson.M{"stage": "IXSCAN"}}},
+				bson.M{"shardName": "rs1", "winningPlan": bson.M{"stage": "COLLSCAN"}},
+			}},
+			[]string{"SHARD_MERGE", "FETCH", "IXSCAN", "COLLSCAN"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := planStages(tt.plan); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("planStages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
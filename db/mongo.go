@@ -2,41 +2,114 @@ package db
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"os"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 
 	"analytics/models"
 )
 
+// MongoConfig holds the MongoDB connection options
+type MongoConfig struct {
+	URI             string
+	Timeout         time.Duration
+	TLSCAFile       string // CA bundle used to verify the server certificate
+	TLSCertFile     string // Client certificate for mutual TLS or MONGODB-X509
+	TLSKeyFile      string // Private key of the client certificate
+	AuthMechanism   string // e.g. SCRAM-SHA-256, MONGODB-X509; empty = from the URI
+	AuthSource      string // Empty = from the URI
+	ReadPreference  string // e.g. secondaryPreferred; empty = from the URI
+	MaxStaleness    time.Duration
+	ReadConcern     string // e.g. local, majority; empty = from the URI
+	Compressors     []string
+	MinPoolSize     uint64
+	MaxPoolSize     uint64
+	MaxConnIdleTime time.Duration
+}
+
+// DefaultMongoConfig returns the default MongoDB connection options
+func DefaultMongoConfig() *MongoConfig {
+	return &MongoConfig{
+		Timeout:         time.Second * 30,
+		MinPoolSize:     10,  // Set minimum connections to avoid slow startup
+		MaxPoolSize:     100, // Adjust connection pool settings
+		MaxConnIdleTime: time.Minute,
+	}
+}
+
 // ConnectMongoDB establishes a connection to MongoDB
-func ConnectMongoDB(mongoURI string, timeoutSec int) (*mongo.Client, error) {
+func ConnectMongoDB(config *MongoConfig) (*mongo.Client, error) {
 	// Set client options
 	clientOptions := options.Client().
-		ApplyURI(mongoURI).
-		SetConnectTimeout(time.Duration(timeoutSec) * time.Second).
-		SetServerSelectionTimeout(time.Duration(timeoutSec) * time.Second).
-		SetSocketTimeout(time.Duration(timeoutSec) * time.Second).
-		SetMaxPoolSize(100).            // Adjust connection pool settings
-		SetMinPoolSize(10).             // Set minimum connections to avoid slow startup
-		SetMaxConnIdleTime(time.Minute) // Close idle connections after 1 minute
+		ApplyURI(config.URI).
+		SetConnectTimeout(config.Timeout).
+		SetServerSelectionTimeout(config.Timeout).
+		SetSocketTimeout(config.Timeout).
+		SetMaxPoolSize(config.MaxPoolSize).
+		SetMinPoolSize(config.MinPoolSize).
+		SetMaxConnIdleTime(config.MaxConnIdleTime)
+
+	if config.TLSCAFile != "" || config.TLSCertFile != "" {
+		tlsConfig, err := mongoTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetTLSConfig(tlsConfig)
+	}
+
+	// Keep credentials from the URI and only override what was configured
+	if config.AuthMechanism != "" || config.AuthSource != "" {
+		credential := options.Credential{}
+		if clientOptions.Auth != nil {
+			credential = *clientOptions.Auth
+		}
+		if config.AuthMechanism != "" {
+			credential.AuthMechanism = config.AuthMechanism
+		}
+		if config.AuthSource != "" {
+			credential.AuthSource = config.AuthSource
+		}
+		clientOptions.SetAuth(credential)
+	}
+
+	// The read preference applies to every read made through this client
+	if config.ReadPreference != "" {
+		readPref, err := parseReadPreference(config.ReadPreference, config.MaxStaleness)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetReadPreference(readPref)
+	}
+
+	if config.ReadConcern != "" {
+		clientOptions.SetReadConcern(&readconcern.ReadConcern{Level: config.ReadConcern})
+	}
+
+	if len(config.Compressors) > 0 {
+		clientOptions.SetCompressors(config.Compressors)
+	}
 
 	// Connect to MongoDB
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
 	defer cancel()
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check the connection with a ping
+	// Check the connection with a ping using the configured read preference
 	pingCtx, pingCancel := context.WithTimeout(context.Background(), time.Duration(5)*time.Second)
 	defer pingCancel()
-	err = client.Ping(pingCtx, readpref.Primary())
+	err = client.Ping(pingCtx, clientOptions.ReadPreference)
 	if err != nil {
 		return nil, fmt.Errorf("failed to ping MongoDB: %v", err)
 	}
@@ -45,8 +118,61 @@ func ConnectMongoDB(mongoURI string, timeoutSec int) (*mongo.Client, error) {
 	return client, nil
 }
 
+// parseReadPreference builds a read preference from its mode name
+func parseReadPreference(name string, maxStaleness time.Duration) (*readpref.ReadPref, error) {
+	mode, err := readpref.ModeFromString(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid read preference %q: %v", name, err)
+	}
+
+	var readPrefOptions []readpref.Option
+	if maxStaleness > 0 {
+		readPrefOptions = append(readPrefOptions, readpref.WithMaxStaleness(maxStaleness))
+	}
+
+	readPref, err := readpref.New(mode, readPrefOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid read preference %q: %v", name, err)
+	}
+	return readPref, nil
+}
+
+// mongoTLSConfig loads the configured CA bundle and client certificate
+func mongoTLSConfig(config *MongoConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if config.TLSCAFile != "" {
+		caData, err := os.ReadFile(config.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading TLS CA file: %v", err)
+		}
+		caPool := x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificates found in TLS CA file %s", config.TLSCAFile)
+		}
+		tlsConfig.RootCAs = caPool
+	}
+
+	if config.TLSCertFile != "" {
+		keyFile := config.TLSKeyFile
+		if keyFile == "" {
+			// Certificate and key are often bundled in one PEM file
+			keyFile = config.TLSCertFile
+		}
+		certificate, err := tls.LoadX509KeyPair(config.TLSCertFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading TLS client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
 // GetEventRecoveries retrieves EventRecovery documents from MongoDB
-func GetEventRecoveries(db *mongo.Database, collectionName string, limit int, timeoutSec int) ([]models.EventRecovery, error) {
+// With skipVerified, documents whose last validation found every event are left out.
+// A non-zero since limits the query to documents created at or after it.
+func GetEventRecoveries(db *mongo.Database, collectionName string, limit int, timeoutSec int, skipVerified bool, since time.Time) ([]models.EventRecovery, error) {
 	var eventRecoveries []models.EventRecovery
 	collection := db.Collection(collectionName)
 
@@ -63,8 +189,17 @@ func GetEventRecoveries(db *mongo.Database, collectionName string, limit int, ti
 		findOptions.SetLimit(int64(limit))
 	}
 
+	filter := bson.M{}
+	if skipVerified {
+		filter = UnverifiedFilter()
+	}
+	if !since.IsZero() {
+		// ObjectIDs start with their creation time
+		filter["_id"] = bson.M{"$gte": primitive.NewObjectIDFromTimestamp(since)}
+	}
+
 	// Find documents
-	cursor, err := collection.Find(ctx, bson.M{}, findOptions)
+	cursor, err := collection.Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -90,3 +225,119 @@ func GetEventRecoveries(db *mongo.Database, collectionName string, limit int, ti
 
 	return eventRecoveries, nil
 }
+
+// ListCollectionNames returns the set of collection names in the database
+func ListCollectionNames(db *mongo.Database, timeoutSec int) (map[string]bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+	defer cancel()
+
+	names, err := db.ListCollectionNames(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	collections := make(map[string]bool, len(names))
+	for _, name := range names {
+		collections[name] = true
+	}
+	return collections, nil
+}
+
+// SampleEventRecoveries decodes up to limit documents from the source collection.
+// Documents that fail to decode are reported as errors instead of aborting the sample.
+func SampleEventRecoveries(db *mongo.Database, collectionName string, limit int, timeoutSec int) ([]models.EventRecovery, []error, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+	defer cancel()
+
+	cursor, err := db.Collection(collectionName).Find(ctx, bson.M{}, options.Find().SetLimit(int64(limit)))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var eventRecoveries []models.EventRecovery
+	var decodeErrors []error
+	for cursor.Next(ctx) {
+		var eventRecovery models.EventRecovery
+		if err := cursor.Decode(&eventRecovery); err != nil {
+			decodeErrors = append(decodeErrors, fmt.Errorf("document %v: %v", cursor.Current.Lookup("_id"), err))
+			continue
+		}
+		eventRecoveries = append(eventRecoveries, eventRecovery)
+	}
+
+	return eventRecoveries, decodeErrors, cursor.Err()
+}
+
+// EstimateDocumentCount returns the collection's document count from its metadata
+func EstimateDocumentCount(db *mongo.Database, collectionName string, timeoutSec int) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+	defer cancel()
+
+	return db.Collection(collectionName).EstimatedDocumentCount(ctx)
+}
+
+// UnverifiedFilter matches recovery documents that have not yet been fully verified
+func UnverifiedFilter() bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"validation": bson.M{"$exists": false}},
+		bson.M{"validation.missing": bson.M{"$gt": 0}},
+		bson.M{"validation.errors": bson.M{"$gt": 0}},
+	}}
+}
+
+// WriteValidationStatus sets the validation sub-document of a recovery document
+func WriteValidationStatus(db *mongo.Database, collectionName string, id primitive.ObjectID, status models.ValidationStatus, timeoutSec int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+	defer cancel()
+
+	_, err := db.Collection(collectionName).UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"validation": status}},
+	)
+	return err
+}
+
+// ArchivableFilter matches recovery documents older than minAge whose last
+// written-back validation found every one of their events
+func ArchivableFilter(minAge time.Duration) bson.M {
+	cutoff := primitive.NewObjectIDFromTimestamp(time.Now().Add(-minAge))
+	return bson.M{
+		"_id":                bson.M{"$lt": cutoff},
+		"validation.missing": 0,
+		"validation.errors":  0,
+		"validation.found":   bson.M{"$gt": 0},
+		"$expr": bson.M{"$eq": bson.A{
+			"$validation.found",
+			bson.M{"$size": bson.M{"$ifNull": bson.A{"$event", bson.A{}}}},
+		}},
+	}
+}
+
+// FindArchivable returns a cursor over the raw recovery documents matching ArchivableFilter
+func FindArchivable(ctx context.Context, db *mongo.Database, collectionName string, minAge time.Duration, limit int) (*mongo.Cursor, error) {
+	findOptions := options.Find().
+		SetNoCursorTimeout(true).
+		SetBatchSize(100).
+		SetSort(bson.M{"_id": 1})
+	if limit > 0 {
+		findOptions.SetLimit(int64(limit))
+	}
+	return db.Collection(collectionName).Find(ctx, ArchivableFilter(minAge), findOptions)
+}
+
+// DeleteArchived deletes archived recovery documents from the source collection.
+// The archivable filter is applied again so documents revalidated in the meantime are kept.
+func DeleteArchived(db *mongo.Database, collectionName string, ids bson.A, minAge time.Duration, timeoutSec int) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+	defer cancel()
+
+	filter := ArchivableFilter(minAge)
+	filter["_id"] = bson.M{"$in": ids}
+
+	result, err := db.Collection(collectionName).DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -1,12 +1,19 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 
+	"analytics/extract"
 	"analytics/models"
 )
 
@@ -16,6 +23,12 @@ type MySQLConfig struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+	QueryTimeout    time.Duration // Timeout for each batched query
+	MaxConcurrent   int           // Maximum number of batched queries in flight
+	BatchSize       int           // Maximum number of session IDs per IN (...) list
+	ScreenColumns   []string      // app_tracking_new columns read for screen matching, see EventNameMap.Columns
+	TimeWindow      time.Duration // Tolerance between the event time and the matched row, 0 = disabled
+	EventTime       extract.TimeExtractor
 }
 
 // Defult Mysql config returns mysql config
@@ -25,9 +38,27 @@ func DefultMySQLConfig() *MySQLConfig {
 		MaxOpenConns:    10,
 		MaxIdleConns:    5,
 		ConnMaxLifetime: time.Minute * 3,
+		QueryTimeout:    time.Second * 15,
+		MaxConcurrent:   4,
+		BatchSize:       200,
+		TimeWindow:      time.Hour * 6,
+		EventTime:       extract.IDPrefixTime{Digits: 13},
 	}
 }
 
+// mysqlIdentifier matches column names that are safe to interpolate into a query
+var mysqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateMySQLColumns checks that configured column names are plain identifiers
+func ValidateMySQLColumns(columns []string) error {
+	for _, column := range columns {
+		if !mysqlIdentifier.MatchString(column) {
+			return fmt.Errorf("invalid MySQL column name: %q", column)
+		}
+	}
+	return nil
+}
+
 // ConnectMySQL establishes a connection to MySQL database
 func ConnectMySQL(config *MySQLConfig) (*sql.DB, error) {
 	db, err := sql.Open("mysql", config.DSN)
@@ -41,7 +72,9 @@ func ConnectMySQL(config *MySQLConfig) (*sql.DB, error) {
 	db.SetConnMaxLifetime(config.ConnMaxLifetime)
 
 	// Test the connection
-	err = db.Ping()
+	ctx, cancel := context.WithTimeout(context.Background(), config.QueryTimeout)
+	defer cancel()
+	err = db.PingContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to database: %v", err)
 	}
@@ -50,6 +83,52 @@ func ConnectMySQL(config *MySQLConfig) (*sql.DB, error) {
 	return db, nil
 }
 
+// CheckMySQLTable returns the app_tracking_new columns the checks need but the table lacks
+func CheckMySQLTable(db *sql.DB, config *MySQLConfig) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.QueryTimeout)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = 'docquity_analytics'
+		  AND table_name = 'app_tracking_new'
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading table columns: %v", err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, fmt.Errorf("error scanning column name: %v", err)
+		}
+		existing[strings.ToLower(column)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		return nil, fmt.Errorf("table docquity_analytics.app_tracking_new does not exist")
+	}
+
+	required := []string{
+		"id", "event_name", "product_type", "product_type_id", "session_id",
+		"track_id", "session_start_time", "session_end_time", "date_of_creation",
+	}
+	required = append(required, config.ScreenColumns...)
+
+	var missing []string
+	for _, column := range required {
+		if !existing[strings.ToLower(column)] {
+			missing = append(missing, column)
+		}
+	}
+	return missing, nil
+}
+
 // EventCollectionMap maps collection names to product_type_id
 type EventCollectionMap map[string]int
 
@@ -62,125 +141,420 @@ func DefaultEventCollectionMap() EventCollectionMap {
 	}
 }
 
-// EventNameMap maps event names to expected screen names
-type EventNameMap map[string][]string
+// ScreenMatch maps app_tracking_new columns to the screen value each must hold
+type ScreenMatch map[string]string
+
+// EventNameMap maps event names to the screens their MySQL rows must carry.
+// Only events listed here are checked in MySQL.
+type EventNameMap map[string]ScreenMatch
 
-// DefaultEventNameMap returns a default mapping of event names to screen names
+// DefaultEventNameMap returns a default mapping of event names to screens
 func DefaultEventNameMap() EventNameMap {
 	return EventNameMap{
-		"DETAIL_EXIT": {"CURRENT_SCREEN", "SERIES_DETAIL"},
+		"DETAIL_EXIT": {"current_screen": "SERIES_DETAIL"},
 		// Add more mappings as needed
 	}
 }
 
-// CheckEventInMySQL checks if an event exists in MySQL database
-func CheckEventInMySQL(db *sql.DB, event models.Event, collectionMap EventCollectionMap, eventNameMap EventNameMap) (*models.MySQLEventResult, error) {
-	// Initialize result
+// Columns returns every screen column the map refers to, sorted
+func (m EventNameMap) Columns() []string {
+	seen := make(map[string]bool)
+	var columns []string
+	for _, match := range m {
+		for column := range match {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// newMySQLEventResult initializes the result of a MySQL check for an event
+func newMySQLEventResult(event models.Event, eventNameMap EventNameMap, config *MySQLConfig) *models.MySQLEventResult {
 	result := &models.MySQLEventResult{
-		EventID:        event.ID,
-		EventName:      event.EventName,
-		CollectionName: event.EntityType,
-		SessionID:      event.SessionID,
-		Found:          false,
+		EventID:         event.ID,
+		EventName:       event.EventName,
+		CollectionName:  event.EntityType,
+		SessionID:       event.SessionID,
+		Found:           false,
+		Outcome:         models.MySQLNotFound,
+		ExpectedScreens: eventNameMap[event.EventName],
 	}
 
-	// Check if we need to verify this event in MySQL
-	if len(eventNameMap[event.EventName]) == 0 {
-		// No verification needed for this event name
-		return result, nil
+	if config.EventTime != nil {
+		if eventTime, ok := config.EventTime.EventTime(event); ok {
+			result.EventTime = eventTime
+		}
+	}
+	return result
+}
+
+// CombineResults pairs every MongoDB result with the MySQL result for the same event.
+// MySQLResult is nil for events that did not need a MySQL check.
+func CombineResults(mongoResults []models.Result, mysqlResults []*models.MySQLEventResult) []models.CombinedResult {
+	mysqlByID := make(map[string]*models.MySQLEventResult, len(mysqlResults))
+	for _, mysqlResult := range mysqlResults {
+		mysqlByID[mysqlResult.EventID] = mysqlResult
 	}
 
-	// Get product_type_id for the collection
-	productTypeID, ok := collectionMap[event.EntityType]
-	if !ok {
-		return result, fmt.Errorf("no product_type_id mapping for collection: %s", event.EntityType)
+	combined := make([]models.CombinedResult, 0, len(mongoResults))
+	for _, mongoResult := range mongoResults {
+		combined = append(combined, models.CombinedResult{
+			MongoResult: mongoResult,
+			MySQLResult: mysqlByID[mongoResult.EventID],
+		})
 	}
+	return combined
+}
+
+// mysqlGroupKey identifies a batch of events that share the same query parameters
+type mysqlGroupKey struct {
+	productType int
+	eventName   string
+}
 
-	// Construct and execute the query
+// mysqlStatements caches one prepared statement per IN (...) list length
+type mysqlStatements struct {
+	db     *sql.DB
+	config *MySQLConfig
+	mu     sync.Mutex
+	stmts  map[int]*sql.Stmt
+}
+
+// newMySQLStatements creates an empty statement cache
+func newMySQLStatements(db *sql.DB, config *MySQLConfig) *mysqlStatements {
+	return &mysqlStatements{db: db, config: config, stmts: make(map[int]*sql.Stmt)}
+}
+
+// get returns the prepared statement for a batch of the given size
+func (s *mysqlStatements) get(ctx context.Context, size int) (*sql.Stmt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stmt, ok := s.stmts[size]; ok {
+		return stmt, nil
+	}
+
+	screenColumns := ""
+	for _, column := range s.config.ScreenColumns {
+		screenColumns += ", " + column
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", size), ",")
 	query := `
-		SELECT id, event_name, product_type, product_type_id, session_id, 
-		       track_id, session_start_time, session_end_time, date_of_creation 
+		SELECT id, event_name, product_type, product_type_id, session_id,
+		       track_id, session_start_time, session_end_time, date_of_creation` + screenColumns + `
 		FROM docquity_analytics.app_tracking_new
-		WHERE product_type = ? 
+		WHERE product_type = ?
 		  AND event_name = ?
-		  AND session_id = ?
-		ORDER BY id DESC LIMIT 1
+		  AND session_id IN (` + placeholders + `)
+		ORDER BY id DESC
 	`
 
-	row := db.QueryRow(query, productTypeID, event.EventName, event.SessionID)
-
-	// Scan the result into a struct
-	var mysqlEvent models.MySQLEvent
-	err := row.Scan(
-		&mysqlEvent.ID,
-		&mysqlEvent.EventName,
-		&mysqlEvent.ProductType,
-		&mysqlEvent.ProductTypeID,
-		&mysqlEvent.SessionID,
-		&mysqlEvent.TrackID,
-		&mysqlEvent.SessionStartTime,
-		&mysqlEvent.SessionEndTime,
-		&mysqlEvent.DateOfCreation,
-	)
+	stmt, err := s.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error preparing MySQL query: %v", err)
+	}
+	s.stmts[size] = stmt
+	return stmt, nil
+}
+
+// query returns every matching row per session ID, newest first
+func (s *mysqlStatements) query(ctx context.Context, key mysqlGroupKey, sessionIDs []string) (map[string][]models.MySQLEvent, error) {
+	stmt, err := s.get(ctx, len(sessionIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{key.productType, key.eventName}
+	for _, sessionID := range sessionIDs {
+		args = append(args, sessionID)
+	}
 
+	rows, err := stmt.QueryContext(ctx, args...)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			// No matching record found
-			return result, nil
+		return nil, fmt.Errorf("error querying MySQL: %v", err)
+	}
+	defer rows.Close()
+
+	sessions := make(map[string][]models.MySQLEvent)
+	for rows.Next() {
+		var mysqlEvent models.MySQLEvent
+		var sessionStart, sessionEnd, createdAt sql.NullTime
+		screens := make([]sql.NullString, len(s.config.ScreenColumns))
+
+		dest := []interface{}{
+			&mysqlEvent.ID,
+			&mysqlEvent.EventName,
+			&mysqlEvent.ProductType,
+			&mysqlEvent.ProductTypeID,
+			&mysqlEvent.SessionID,
+			&mysqlEvent.TrackID,
+			&sessionStart,
+			&sessionEnd,
+			&createdAt,
+		}
+		for i := range screens {
+			dest = append(dest, &screens[i])
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("error scanning MySQL row: %v", err)
+		}
+
+		// NULL times become zero times, which rowTimeDelta skips
+		mysqlEvent.SessionStartTime = sessionStart.Time
+		mysqlEvent.SessionEndTime = sessionEnd.Time
+		mysqlEvent.DateOfCreation = createdAt.Time
+
+		if len(screens) > 0 {
+			mysqlEvent.Screens = make(map[string]string, len(screens))
+			for i, column := range s.config.ScreenColumns {
+				mysqlEvent.Screens[column] = screens[i].String
+			}
 		}
-		return result, fmt.Errorf("error querying MySQL: %v", err)
+
+		sessions[mysqlEvent.SessionID] = append(sessions[mysqlEvent.SessionID], mysqlEvent)
 	}
 
-	// Event was found in MySQL
-	result.Found = true
-	result.MySQLEvent = &mysqlEvent
-
-	return result, nil
-}
-
-// ValidateAndCheckEvents validates events in both MongoDB and MySQL
-func ValidateAndCheckEvents(
-	mongoEvent models.Event,
-	mongoResult models.Result,
-	mysqlDB *sql.DB,
-	collectionMap EventCollectionMap,
-	eventNameMap EventNameMap,
-) models.CombinedResult {
-	combined := models.CombinedResult{
-		MongoResult: mongoResult,
-		MySQLResult: nil,
-	}
-
-	// Only check MySQL if the event matches our criteria
-	shouldCheckMySQL := false
-	if screenNames, ok := eventNameMap[mongoEvent.EventName]; ok && len(screenNames) > 0 {
-		shouldCheckMySQL = true
-	}
-
-	if shouldCheckMySQL {
-		mysqlResult, err := CheckEventInMySQL(mysqlDB, mongoEvent, collectionMap, eventNameMap)
-		if err != nil {
-			fmt.Printf("Error checking MySQL for event %s: %v\n", mongoEvent.ID, err)
-			mysqlResult = &models.MySQLEventResult{
-				EventID:        mongoEvent.ID,
-				EventName:      mongoEvent.EventName,
-				CollectionName: mongoEvent.EntityType,
-				SessionID:      mongoEvent.SessionID,
-				Found:          false,
-				Error:          err,
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading MySQL rows: %v", err)
+	}
+	return sessions, nil
+}
+
+// close closes every cached statement
+func (s *mysqlStatements) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stmt := range s.stmts {
+		stmt.Close()
+	}
+}
+
+// resolveMySQLResult picks the row that matches an event from the session's rows.
+// Candidates are the rows whose screen columns hold the expected screens. With a time window configured,
+// the candidate closest to the event time wins and must lie within the window;
+// otherwise the newest candidate wins.
+func resolveMySQLResult(result *models.MySQLEventResult, rows []models.MySQLEvent, config *MySQLConfig) {
+	if len(rows) == 0 {
+		result.Outcome = models.MySQLNotFound
+		return
+	}
+
+	var candidates []models.MySQLEvent
+	for _, row := range rows {
+		if matchScreen(row, result.ExpectedScreens) {
+			candidates = append(candidates, row)
+		}
+	}
+
+	if len(candidates) == 0 {
+		row := rows[0]
+		result.Found = false
+		result.Outcome = models.MySQLUnexpectedScreen
+		result.MySQLEvent = &row
+		return
+	}
+
+	// Rows are newest first, so without a time window the first candidate wins
+	best := candidates[0]
+	checkWindow := config.TimeWindow > 0 && !result.EventTime.IsZero()
+	if checkWindow {
+		bestDelta := rowTimeDelta(best, result.EventTime)
+		for _, row := range candidates[1:] {
+			if delta := rowTimeDelta(row, result.EventTime); delta < bestDelta {
+				best, bestDelta = row, delta
 			}
 		}
-		combined.MySQLResult = mysqlResult
+	}
+	if !result.EventTime.IsZero() {
+		result.TimeDelta = rowTimeDelta(best, result.EventTime)
+	}
+
+	result.MySQLEvent = &best
+	result.MatchedScreens = make(map[string]string, len(result.ExpectedScreens))
+	for column := range result.ExpectedScreens {
+		result.MatchedScreens[column] = best.Screens[column]
+	}
+
+	if checkWindow && result.TimeDelta > config.TimeWindow {
+		result.Found = false
+		result.Outcome = models.MySQLSuspicious
+		return
+	}
+
+	result.Found = true
+	result.Outcome = models.MySQLFound
+}
+
+// rowTimeDelta returns how far the event time lies outside the row's session,
+// or from its date_of_creation, whichever is closer
+func rowTimeDelta(row models.MySQLEvent, eventTime time.Time) time.Duration {
+	delta := time.Duration(math.MaxInt64)
+
+	if !row.SessionStartTime.IsZero() {
+		end := row.SessionEndTime
+		if end.Before(row.SessionStartTime) {
+			end = row.SessionStartTime
+		}
 
-		// Log the result
-		if mysqlResult.Error != nil {
-			fmt.Printf("Error checking MySQL for event %s: %v\n", mysqlResult.EventID, mysqlResult.Error)
-		} else if mysqlResult.Found {
-			fmt.Printf("✅ Event %s found in MySQL database\n", mysqlResult.EventID)
-		} else {
-			fmt.Printf("❌ Event %s NOT found in MySQL database\n", mysqlResult.EventID)
+		switch {
+		case eventTime.Before(row.SessionStartTime):
+			delta = row.SessionStartTime.Sub(eventTime)
+		case eventTime.After(end):
+			delta = eventTime.Sub(end)
+		default:
+			return 0
 		}
 	}
 
-	return combined
+	if !row.DateOfCreation.IsZero() {
+		creationDelta := eventTime.Sub(row.DateOfCreation)
+		if creationDelta < 0 {
+			creationDelta = -creationDelta
+		}
+		if creationDelta < delta {
+			delta = creationDelta
+		}
+	}
+
+	return delta
+}
+
+// matchScreen reports whether every expected screen column of the row holds its screen
+func matchScreen(row models.MySQLEvent, expectedScreens map[string]string) bool {
+	for column, screen := range expectedScreens {
+		if !strings.EqualFold(strings.TrimSpace(row.Screens[column]), screen) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckEventsInMySQL checks many events in MySQL at once. Events are grouped by
+// (product_type, event_name) and their session IDs are looked up in batches
+// through prepared statements, with at most config.MaxConcurrent queries running.
+// Events that do not need a MySQL check are skipped; the returned results are in input order.
+func CheckEventsInMySQL(db *sql.DB, events []models.Event, collectionMap EventCollectionMap, eventNameMap EventNameMap, config *MySQLConfig) []*models.MySQLEventResult {
+	results, groups, groupOrder := groupMySQLEvents(events, collectionMap, eventNameMap, config)
+
+	statements := newMySQLStatements(db, config)
+	defer statements.close()
+
+	// Create a semaphore to limit concurrency
+	semaphore := make(chan struct{}, config.MaxConcurrent)
+	var wg sync.WaitGroup
+
+	for _, key := range groupOrder {
+		for _, batch := range batchBySession(groups[key], config.BatchSize) {
+			wg.Add(1)
+			semaphore <- struct{}{}
+
+			go func(key mysqlGroupKey, batch map[string][]*models.MySQLEventResult) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+
+				checkMySQLBatch(statements, key, batch)
+			}(key, batch)
+		}
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// FailEventsInMySQL marks every event that needs a MySQL check as errored with err,
+// used when the database cannot be reached so the run can still report those events.
+func FailEventsInMySQL(events []models.Event, collectionMap EventCollectionMap, eventNameMap EventNameMap, config *MySQLConfig, err error) []*models.MySQLEventResult {
+	results, _, _ := groupMySQLEvents(events, collectionMap, eventNameMap, config)
+	for _, result := range results {
+		if result.Error == nil {
+			result.Outcome = models.MySQLError
+			result.Error = err
+		}
+	}
+	return results
+}
+
+// groupMySQLEvents initializes a result for every event that needs a MySQL check and groups
+// the checkable ones by product type and event name, keeping the order groups were first seen
+func groupMySQLEvents(events []models.Event, collectionMap EventCollectionMap, eventNameMap EventNameMap, config *MySQLConfig) ([]*models.MySQLEventResult, map[mysqlGroupKey][]*models.MySQLEventResult, []mysqlGroupKey) {
+	var results []*models.MySQLEventResult
+	groups := make(map[mysqlGroupKey][]*models.MySQLEventResult)
+	var groupOrder []mysqlGroupKey
+
+	for _, event := range events {
+		// Check if we need to verify this event in MySQL
+		if len(eventNameMap[event.EventName]) == 0 {
+			continue
+		}
+
+		result := newMySQLEventResult(event, eventNameMap, config)
+		results = append(results, result)
+
+		// Get product_type_id for the collection
+		productTypeID, ok := collectionMap[event.EntityType]
+		if !ok {
+			result.Outcome = models.MySQLError
+			result.Error = fmt.Errorf("no product_type_id mapping for collection: %s", event.EntityType)
+			continue
+		}
+		result.ProductType = productTypeID
+
+		key := mysqlGroupKey{productType: productTypeID, eventName: event.EventName}
+		if _, ok := groups[key]; !ok {
+			groupOrder = append(groupOrder, key)
+		}
+		groups[key] = append(groups[key], result)
+	}
+	return results, groups, groupOrder
+}
+
+// batchBySession splits results into batches of at most batchSize distinct session IDs
+func batchBySession(results []*models.MySQLEventResult, batchSize int) []map[string][]*models.MySQLEventResult {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	var batches []map[string][]*models.MySQLEventResult
+	current := make(map[string][]*models.MySQLEventResult)
+	for _, result := range results {
+		if _, ok := current[result.SessionID]; !ok && len(current) == batchSize {
+			batches = append(batches, current)
+			current = make(map[string][]*models.MySQLEventResult)
+		}
+		current[result.SessionID] = append(current[result.SessionID], result)
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+// checkMySQLBatch runs one batched query under its own timeout and resolves every result in the batch
+func checkMySQLBatch(statements *mysqlStatements, key mysqlGroupKey, batch map[string][]*models.MySQLEventResult) {
+	ctx, cancel := context.WithTimeout(context.Background(), statements.config.QueryTimeout)
+	defer cancel()
+
+	sessionIDs := make([]string, 0, len(batch))
+	for sessionID := range batch {
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+
+	rows, err := statements.query(ctx, key, sessionIDs)
+	for sessionID, sessionResults := range batch {
+		for _, result := range sessionResults {
+			if err != nil {
+				result.Outcome = models.MySQLError
+				result.Error = err
+				continue
+			}
+			resolveMySQLResult(result, rows[sessionID], statements.config)
+		}
+	}
 }
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"analytics/models"
+)
+
+func TestRowTimeDelta(t *testing.T) {
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		row  models.MySQLEvent
+		want time.Duration
+	}{
+		{"inside session", models.MySQLEvent{SessionStartTime: base.Add(-time.Hour), SessionEndTime: base.Add(time.Hour)}, 0},
+		{"before session", models.MySQLEvent{SessionStartTime: base.Add(time.Hour), SessionEndTime: base.Add(2 * time.Hour)}, time.Hour},
+		{"after session", models.MySQLEvent{SessionStartTime: base.Add(-3 * time.Hour), SessionEndTime: base.Add(-2 * time.Hour)}, 2 * time.Hour},
+		{"null session end", models.MySQLEvent{SessionStartTime: base.Add(-30 * time.Minute)}, 30 * time.Minute},
+		{"null session times", models.MySQLEvent{DateOfCreation: base.Add(45 * time.Minute)}, 45 * time.Minute},
+		{"creation closer than session", models.MySQLEvent{
+			SessionStartTime: base.Add(-5 * time.Hour),
+			SessionEndTime:   base.Add(-4 * time.Hour),
+			DateOfCreation:   base.Add(-10 * time.Minute),
+		}, 10 * time.Minute},
+		{"all times null", models.MySQLEvent{}, time.Duration(math.MaxInt64)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rowTimeDelta(tt.row, base); got != tt.want {
+				t.Fatalf("rowTimeDelta = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveMySQLResult(t *testing.T) {
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	expected := map[string]string{"current_screen": "SERIES_DETAIL"}
+
+	// row builds a row of the given screen whose session starts at offset from base
+	row := func(id int64, screen string, offset time.Duration) models.MySQLEvent {
+		return models.MySQLEvent{
+			ID:               id,
+			SessionStartTime: base.Add(offset),
+			SessionEndTime:   base.Add(offset + time.Minute),
+			Screens:          map[string]string{"current_screen": screen},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		rows      []models.MySQLEvent // Newest first, as queried
+		eventTime time.Time
+		window    time.Duration
+		outcome   string
+		rowID     int64
+		delta     time.Duration
+	}{
+		{"no rows", nil, base, time.Hour, models.MySQLNotFound, 0, 0},
+		{"match within window", []models.MySQLEvent{row(1, "SERIES_DETAIL", 0)}, base, time.Hour, models.MySQLFound, 1, 0},
+		{"screen compared case-insensitively", []models.MySQLEvent{row(1, " series_detail ", 0)}, base, time.Hour, models.MySQLFound, 1, 0},
+		{
+			"screen mismatch falls back to the newest row",
+			[]models.MySQLEvent{row(2, "HOME", 0), row(1, "FEED", 0)},
+			base, time.Hour, models.MySQLUnexpectedScreen, 2, 0,
+		},
+		{
+			"closest candidate wins over the newest",
+			[]models.MySQLEvent{row(3, "SERIES_DETAIL", 5*time.Hour), row(2, "HOME", 0), row(1, "SERIES_DETAIL", -10*time.Minute)},
+			base, time.Hour, models.MySQLFound, 1, 9 * time.Minute,
+		},
+		{"out of window", []models.MySQLEvent{row(1, "SERIES_DETAIL", 3*time.Hour)}, base, time.Hour, models.MySQLSuspicious, 1, 3 * time.Hour},
+		{
+			"null row times are out of window",
+			[]models.MySQLEvent{{ID: 1, Screens: map[string]string{"current_screen": "SERIES_DETAIL"}}},
+			base, time.Hour, models.MySQLSuspicious, 1, time.Duration(math.MaxInt64),
+		},
+		{
+			"unknown event time takes the newest candidate",
+			[]models.MySQLEvent{row(2, "SERIES_DETAIL", 5*time.Hour), row(1, "SERIES_DETAIL", 0)},
+			time.Time{}, time.Hour, models.MySQLFound, 2, 0,
+		},
+		{
+			"disabled window takes the newest candidate",
+			[]models.MySQLEvent{row(2, "SERIES_DETAIL", 5*time.Hour), row(1, "SERIES_DETAIL", 0)},
+			base, 0, models.MySQLFound, 2, 5 * time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &models.MySQLEventResult{ExpectedScreens: expected, EventTime: tt.eventTime}
+			resolveMySQLResult(result, tt.rows, &MySQLConfig{TimeWindow: tt.window})
+
+			if result.Outcome != tt.outcome {
+				t.Fatalf("outcome = %s, want %s", result.Outcome, tt.outcome)
+			}
+			if result.Found != (tt.outcome == models.MySQLFound) {
+				t.Fatalf("found = %v for outcome %s", result.Found, result.Outcome)
+			}
+			if tt.rowID == 0 {
+				if result.MySQLEvent != nil {
+					t.Fatalf("matched row %d, want none", result.MySQLEvent.ID)
+				}
+				return
+			}
+			if result.MySQLEvent == nil || result.MySQLEvent.ID != tt.rowID {
+				t.Fatalf("matched row %v, want %d", result.MySQLEvent, tt.rowID)
+			}
+			if result.TimeDelta != tt.delta {
+				t.Fatalf("time delta = %s, want %s", result.TimeDelta, tt.delta)
+			}
+			if tt.outcome == models.MySQLFound && result.MatchedScreens["current_screen"] == "" {
+				t.Fatalf("matched screens %v do not record the current_screen value", result.MatchedScreens)
+			}
+		})
+	}
+}
+
+func TestMatchScreenNeedsEveryColumn(t *testing.T) {
+	row := models.MySQLEvent{Screens: map[string]string{"current_screen": "SERIES_DETAIL", "previous_screen": "HOME"}}
+
+	tests := []struct {
+		name     string
+		expected map[string]string
+		want     bool
+	}{
+		{"single column", map[string]string{"current_screen": "SERIES_DETAIL"}, true},
+		{"every column", map[string]string{"current_screen": "SERIES_DETAIL", "previous_screen": "HOME"}, true},
+		{"screens in the wrong columns", map[string]string{"current_screen": "HOME", "previous_screen": "SERIES_DETAIL"}, false},
+		{"column not read", map[string]string{"screen_name": "SERIES_DETAIL"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchScreen(row, tt.expected); got != tt.want {
+				t.Fatalf("matchScreen = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchBySession(t *testing.T) {
+	results := func(sessionIDs ...string) []*models.MySQLEventResult {
+		var results []*models.MySQLEventResult
+		for _, sessionID := range sessionIDs {
+			results = append(results, &models.MySQLEventResult{SessionID: sessionID})
+		}
+		return results
+	}
+
+	tests := []struct {
+		name      string
+		results   []*models.MySQLEventResult
+		batchSize int
+		want      []map[string]int // Session ID -> results per batch
+	}{
+		{"empty", nil, 2, nil},
+		{"single batch", results("a", "b"), 2, []map[string]int{{"a": 1, "b": 1}}},
+		{"split by distinct sessions", results("a", "b", "c"), 2, []map[string]int{{"a": 1, "b": 1}, {"c": 1}}},
+		{"repeated session stays in its batch", results("a", "b", "a", "c"), 2, []map[string]int{{"a": 2, "b": 1}, {"c": 1}}},
+		{"non-positive size", results("a", "b"), 0, []map[string]int{{"a": 1}, {"b": 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []map[string]int
+			for _, batch := range batchBySession(tt.results, tt.batchSize) {
+				counts := make(map[string]int)
+				for sessionID, batchResults := range batch {
+					counts[sessionID] = len(batchResults)
+				}
+				got = append(got, counts)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("batches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
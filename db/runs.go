@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"analytics/models"
+)
+
+// findingsBatchSize is the number of findings written per InsertMany
+const findingsBatchSize = 1000
+
+// PersistRun stores a run and its findings, creating the collections' indexes if needed
+func PersistRun(db *mongo.Database, runsCollection string, findingsCollection string, run models.ValidationRun, findings []models.Finding, timeoutSec int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+	defer cancel()
+
+	if err := ensureRunIndexes(ctx, db, runsCollection, findingsCollection); err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+
+	if _, err := db.Collection(runsCollection).InsertOne(ctx, run); err != nil {
+		return fmt.Errorf("failed to write run: %v", err)
+	}
+
+	for start := 0; start < len(findings); start += findingsBatchSize {
+		end := start + findingsBatchSize
+		if end > len(findings) {
+			end = len(findings)
+		}
+
+		batch := make([]interface{}, 0, end-start)
+		for _, finding := range findings[start:end] {
+			batch = append(batch, finding)
+		}
+		if _, err := db.Collection(findingsCollection).InsertMany(ctx, batch); err != nil {
+			return fmt.Errorf("failed to write findings: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// ensureRunIndexes creates the indexes used to query run history
+func ensureRunIndexes(ctx context.Context, db *mongo.Database, runsCollection string, findingsCollection string) error {
+	_, err := db.Collection(runsCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "summary.started_at", Value: -1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Collection(findingsCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "run_id", Value: 1}, {Key: "type", Value: 1}}},
+		{Keys: bson.D{{Key: "collection", Value: 1}}},
+		{Keys: bson.D{{Key: "event_id", Value: 1}}},
+	})
+	return err
+}
+
+// PreviousRunSummary returns the summary of the most recent stored run other than runID, or nil if there is none
+func PreviousRunSummary(db *mongo.Database, runsCollection string, runID string, timeoutSec int) (*models.RunSummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+	defer cancel()
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "summary.started_at", Value: -1}})
+	var run models.ValidationRun
+	err := db.Collection(runsCollection).FindOne(ctx, bson.M{"_id": bson.M{"$ne": runID}}, opts).Decode(&run)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run.Summary, nil
+}
@@ -0,0 +1,254 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"analytics/models"
+)
+
+// SQLCheck defines a destination check against a MySQL database. Only the mysql
+// driver is registered, so queries use its "?" bind variables.
+type SQLCheck struct {
+	Name           string            `json:"name"`
+	Driver         string            `json:"driver"`          // Must be mysql, the default when empty
+	DSN            string            `json:"dsn"`             // Environment variables are expanded, e.g. ${MYSQL_DSN}
+	Query          string            `json:"query"`           // Query with named placeholders such as :session_id
+	Evidence       map[string]string `json:"evidence"`        // Result column -> evidence field name, all columns if empty
+	EventNames     []string          `json:"event_names"`     // Only check these event names, all if empty
+	Collections    []string          `json:"collections"`     // Only check these collections, all if empty
+	TimeoutSeconds int               `json:"timeout_seconds"` // Per-query timeout
+	MaxConcurrent  int               `json:"max_concurrent"`  // Maximum number of queries in flight
+
+	query  string   // Query rewritten to "?" bind variables
+	params []string // Event fields bound to the bind variables, in order
+}
+
+// namedPlaceholder matches :name placeholders but not :: casts
+var namedPlaceholder = regexp.MustCompile(`(^|[^:]):([A-Za-z_][A-Za-z0-9_]*)`)
+
+// LoadSQLChecks reads SQL check definitions from a JSON file
+func LoadSQLChecks(path string) ([]*SQLCheck, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading SQL checks: %v", err)
+	}
+
+	var checks []*SQLCheck
+	if err := json.Unmarshal(data, &checks); err != nil {
+		return nil, fmt.Errorf("error parsing SQL checks: %v", err)
+	}
+
+	for _, check := range checks {
+		if err := check.compile(); err != nil {
+			return nil, fmt.Errorf("SQL check %q: %v", check.Name, err)
+		}
+	}
+	return checks, nil
+}
+
+// compile validates the check and rewrites its named placeholders
+func (c *SQLCheck) compile() error {
+	if c.Name == "" || c.DSN == "" || c.Query == "" {
+		return fmt.Errorf("name, dsn and query are required")
+	}
+	if c.Driver == "" {
+		c.Driver = "mysql"
+	}
+	if c.Driver != "mysql" {
+		return fmt.Errorf("unsupported driver %q, only mysql is available", c.Driver)
+	}
+	if c.TimeoutSeconds <= 0 {
+		c.TimeoutSeconds = 15
+	}
+	if c.MaxConcurrent <= 0 {
+		c.MaxConcurrent = 4
+	}
+
+	var bindErr error
+	c.params = nil
+	c.query = namedPlaceholder.ReplaceAllStringFunc(c.Query, func(match string) string {
+		groups := namedPlaceholder.FindStringSubmatch(match)
+		prefix, field := groups[1], groups[2]
+
+		if _, ok := (models.Event{}).FieldValue(field); !ok && bindErr == nil {
+			bindErr = fmt.Errorf("unknown event field in placeholder :%s", field)
+		}
+		c.params = append(c.params, field)
+		return prefix + "?"
+	})
+	return bindErr
+}
+
+// applies reports whether the check should run for an event
+func (c *SQLCheck) applies(event models.Event) bool {
+	return matchesFilter(c.EventNames, event.EventName) && matchesFilter(c.Collections, event.EntityType)
+}
+
+// matchesFilter reports whether value is in filter, an empty filter matches everything
+func matchesFilter(filter []string, value string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, item := range filter {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// SQLChecker runs a single SQL check against its database
+type SQLChecker struct {
+	check *SQLCheck
+	db    *sql.DB
+	stmt  *sql.Stmt
+}
+
+// NewSQLChecker connects to the check's database and prepares its query
+func NewSQLChecker(check *SQLCheck) (*SQLChecker, error) {
+	db, err := sql.Open(check.Driver, os.ExpandEnv(check.DSN))
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %v", err)
+	}
+	db.SetMaxOpenConns(check.MaxConcurrent)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(check.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error connecting to database: %v", err)
+	}
+
+	stmt, err := db.PrepareContext(ctx, check.query)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error preparing query: %v", err)
+	}
+
+	fmt.Printf("Connected to SQL check %s (%s)\n", check.Name, check.Driver)
+	return &SQLChecker{check: check, db: db, stmt: stmt}, nil
+}
+
+// FailEvents marks every event the check applies to as errored with err, used when the
+// check's database cannot be reached so the run can still report those events
+func (c *SQLCheck) FailEvents(events []models.Event, err error) []*models.SQLCheckResult {
+	results := make([]*models.SQLCheckResult, len(events))
+	for i, event := range events {
+		if !c.applies(event) {
+			continue
+		}
+		results[i] = &models.SQLCheckResult{
+			Check:     c.Name,
+			EventID:   event.ID,
+			EventName: event.EventName,
+			Error:     err,
+		}
+	}
+	return results
+}
+
+// Close releases the prepared statement and the connection pool
+func (c *SQLChecker) Close() {
+	c.stmt.Close()
+	c.db.Close()
+}
+
+// CheckEvents runs the check for every applicable event. The returned results are
+// aligned with events; the result is nil for events the check does not apply to.
+func (c *SQLChecker) CheckEvents(events []models.Event) []*models.SQLCheckResult {
+	results := make([]*models.SQLCheckResult, len(events))
+
+	// Create a semaphore to limit concurrency
+	semaphore := make(chan struct{}, c.check.MaxConcurrent)
+	var wg sync.WaitGroup
+
+	for i, event := range events {
+		if !c.check.applies(event) {
+			continue
+		}
+
+		result := &models.SQLCheckResult{
+			Check:     c.check.Name,
+			EventID:   event.ID,
+			EventName: event.EventName,
+		}
+		results[i] = result
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+
+		go func(evt models.Event, result *models.SQLCheckResult) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			result.Found, result.Evidence, result.Error = c.checkEvent(evt)
+		}(event, result)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// checkEvent runs the query for one event and collects the evidence of the first row
+func (c *SQLChecker) checkEvent(event models.Event) (bool, map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.check.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	args := make([]interface{}, 0, len(c.check.params))
+	for _, field := range c.check.params {
+		value, _ := event.FieldValue(field)
+		args = append(args, value)
+	}
+
+	rows, err := c.stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return false, nil, fmt.Errorf("error querying %s: %v", c.check.Name, err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return false, nil, rows.Err()
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return false, nil, err
+	}
+
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return false, nil, fmt.Errorf("error scanning %s row: %v", c.check.Name, err)
+	}
+
+	evidence := make(map[string]string)
+	for i, column := range columns {
+		field := column
+		if len(c.check.Evidence) > 0 {
+			mapped, ok := c.check.Evidence[strings.ToLower(column)]
+			if !ok {
+				mapped, ok = c.check.Evidence[column]
+			}
+			if !ok {
+				continue
+			}
+			field = mapped
+		}
+		evidence[field] = string(values[i])
+	}
+
+	return true, evidence, nil
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSQLCheckCompile(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		want   string
+		params []string
+	}{
+		{
+			"named placeholders",
+			"SELECT id FROM events WHERE session_id = :session_id AND event_name = :event_name",
+			"SELECT id FROM events WHERE session_id = ? AND event_name = ?",
+			[]string{"session_id", "event_name"},
+		},
+		{"placeholder at the start", ":id", "?", []string{"id"}},
+		{
+			"adjacent placeholders",
+			"SELECT id FROM events WHERE (uuid, session_id) = (:uuid,:session_id)",
+			"SELECT id FROM events WHERE (uuid, session_id) = (?,?)",
+			[]string{"uuid", "session_id"},
+		},
+		{"repeated placeholder", "SELECT :id, :id", "SELECT ?, ?", []string{"id", "id"}},
+		{
+			"casts are left alone",
+			"SELECT created_at::date FROM events WHERE code = :entity_code::text",
+			"SELECT created_at::date FROM events WHERE code = ?::text",
+			[]string{"entity_code"},
+		},
+		{"no placeholders", "SELECT 1", "SELECT 1", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := &SQLCheck{Name: "test", DSN: "dsn", Query: tt.query}
+			if err := check.compile(); err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			if check.query != tt.want {
+				t.Fatalf("query = %q, want %q", check.query, tt.want)
+			}
+			if !reflect.DeepEqual(check.params, tt.params) {
+				t.Fatalf("params = %v, want %v", check.params, tt.params)
+			}
+			if check.Driver != "mysql" {
+				t.Fatalf("driver = %q, want mysql by default", check.Driver)
+			}
+		})
+	}
+}
+
+func TestSQLCheckCompileErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		check SQLCheck
+	}{
+		{"missing query", SQLCheck{Name: "test", DSN: "dsn"}},
+		{"missing dsn", SQLCheck{Name: "test", Query: "SELECT 1"}},
+		{"unregistered driver", SQLCheck{Name: "test", Driver: "postgres", DSN: "dsn", Query: "SELECT 1"}},
+		{"unknown event field", SQLCheck{Name: "test", DSN: "dsn", Query: "SELECT id FROM events WHERE user_id = :user_id"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := tt.check
+			if err := check.compile(); err == nil {
+				t.Fatalf("compile accepted %+v", tt.check)
+			}
+		})
+	}
+}
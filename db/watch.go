@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"analytics/models"
+)
+
+// RecoveryInsert is a change stream event for a newly inserted recovery document
+type RecoveryInsert struct {
+	FullDocument models.EventRecovery `bson:"fullDocument"`
+	ClusterTime  primitive.Timestamp  `bson:"clusterTime"`
+}
+
+// InsertedAt returns when the document was inserted, according to the cluster
+func (c RecoveryInsert) InsertedAt() time.Time {
+	return time.Unix(int64(c.ClusterTime.T), 0)
+}
+
+// resumeTokenDoc stores the last processed change stream position of a watched collection
+type resumeTokenDoc struct {
+	Collection string    `bson:"_id"`
+	Token      bson.Raw  `bson:"token"`
+	UpdatedAt  time.Time `bson:"updated_at"`
+}
+
+// WatchEventRecoveries opens a change stream of documents inserted into the collection,
+// resuming after resumeToken when it is not nil. Change streams need a replica set.
+func WatchEventRecoveries(ctx context.Context, db *mongo.Database, collectionName string, resumeToken bson.Raw) (*mongo.ChangeStream, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"operationType": "insert"}}},
+	}
+
+	streamOptions := options.ChangeStream()
+	if resumeToken != nil {
+		streamOptions.SetResumeAfter(resumeToken)
+	}
+
+	return db.Collection(collectionName).Watch(ctx, pipeline, streamOptions)
+}
+
+// LoadResumeToken returns the stored resume token of the watched collection, or nil if there is none
+func LoadResumeToken(db *mongo.Database, tokensCollection string, watchedCollection string, timeoutSec int) (bson.Raw, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+	defer cancel()
+
+	var doc resumeTokenDoc
+	err := db.Collection(tokensCollection).FindOne(ctx, bson.M{"_id": watchedCollection}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.Token, nil
+}
+
+// SaveResumeToken stores the resume token of the watched collection
+func SaveResumeToken(db *mongo.Database, tokensCollection string, watchedCollection string, token bson.Raw, timeoutSec int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSec)*time.Second)
+	defer cancel()
+
+	doc := resumeTokenDoc{Collection: watchedCollection, Token: token, UpdatedAt: time.Now()}
+	_, err := db.Collection(tokensCollection).ReplaceOne(ctx, bson.M{"_id": watchedCollection}, doc, options.Replace().SetUpsert(true))
+	return err
+}
@@ -0,0 +1,55 @@
+package extract
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"analytics/models"
+)
+
+// TimeExtractor derives the time an event was created
+type TimeExtractor interface {
+	EventTime(event models.Event) (time.Time, bool)
+}
+
+// IDPrefixTime parses the millisecond epoch timestamp at the start of an event ID,
+// e.g. 2025-02-04T18:06:45.621Z from "17386928056217323744021"
+type IDPrefixTime struct {
+	Digits int // Number of leading digits holding the timestamp
+}
+
+// EventTime implements TimeExtractor
+func (t IDPrefixTime) EventTime(event models.Event) (time.Time, bool) {
+	return ParseIDTime(event.ID, t.Digits)
+}
+
+// ParseIDTime parses the millisecond timestamp in the first digits of an ID
+func ParseIDTime(id string, digits int) (time.Time, bool) {
+	if digits <= 0 || len(id) < digits {
+		return time.Time{}, false
+	}
+
+	millis, err := strconv.ParseInt(id[:digits], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	eventTime := time.UnixMilli(millis).UTC()
+	// Reject values that cannot be a real event time
+	if eventTime.Year() < 2000 || eventTime.After(time.Now().Add(24*time.Hour)) {
+		return time.Time{}, false
+	}
+	return eventTime, true
+}
+
+// NewTimeExtractor returns the event time extractor with the given name
+func NewTimeExtractor(name string) (TimeExtractor, error) {
+	switch name {
+	case "id-prefix":
+		return IDPrefixTime{Digits: 13}, nil
+	case "none", "":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown event time source %q", name)
+}
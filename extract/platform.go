@@ -0,0 +1,74 @@
+package extract
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// UnknownPlatform is reported for session IDs that do not encode a platform
+const UnknownPlatform = "unknown"
+
+// PlatformExtractor derives the client platform from a session ID
+type PlatformExtractor interface {
+	Platform(sessionID string) string
+}
+
+// PrefixPlatform takes the platform from the text before the first separator,
+// e.g. "web" from "web-17386928031699464630497"
+type PrefixPlatform struct {
+	Separator string
+}
+
+// Platform implements PlatformExtractor
+func (p PrefixPlatform) Platform(sessionID string) string {
+	prefix, _, ok := strings.Cut(sessionID, p.Separator)
+	if !ok || prefix == "" {
+		return UnknownPlatform
+	}
+	return strings.ToLower(prefix)
+}
+
+// RegexPlatform takes the platform from the first capture group of a regular
+// expression, or from the group named "platform" if there is one
+type RegexPlatform struct {
+	re    *regexp.Regexp
+	group int
+}
+
+// NewRegexPlatform compiles a regex platform extractor
+func NewRegexPlatform(pattern string) (*RegexPlatform, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if re.NumSubexp() == 0 {
+		return nil, fmt.Errorf("pattern %q has no capture group", pattern)
+	}
+
+	group := 1
+	if named := re.SubexpIndex("platform"); named > 0 {
+		group = named
+	}
+	return &RegexPlatform{re: re, group: group}, nil
+}
+
+// Platform implements PlatformExtractor
+func (p *RegexPlatform) Platform(sessionID string) string {
+	match := p.re.FindStringSubmatch(sessionID)
+	if match == nil || match[p.group] == "" {
+		return UnknownPlatform
+	}
+	return strings.ToLower(match[p.group])
+}
+
+// NewPlatformExtractor returns a regex extractor if a pattern is given, otherwise a prefix extractor
+func NewPlatformExtractor(separator, pattern string) (PlatformExtractor, error) {
+	if pattern != "" {
+		return NewRegexPlatform(pattern)
+	}
+	if separator == "" {
+		return nil, fmt.Errorf("platform separator must not be empty")
+	}
+	return PrefixPlatform{Separator: separator}, nil
+}
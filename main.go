@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"os"
 	"runtime"
 	"time"
 
@@ -11,20 +13,42 @@ import (
 	"analytics/db"
 	"analytics/models"
 	"analytics/report"
+	"analytics/sink"
 	"analytics/validator"
 
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// Exit codes, documented in the usage text. log.Fatal exits with exitFailure
+// and the flag package exits with 2 on invalid flags.
+const (
+	exitClean   = 0
+	exitFailure = 1
+	exitMissing = 3
+	exitErrors  = 4
+)
+
 func main() {
+	os.Exit(run())
+}
+
+// run executes the selected command and returns the exit code
+func run() int {
 	// Define and parse configuration
 	cfg := config.ParseFlags()
 
+	// Alerts on a saved report need no database
+	if cfg.Command == config.CommandAlerts {
+		runAlerts(cfg)
+		return exitClean
+	}
+
 	// Print configuration
 	printConfiguration(cfg)
 
 	// Connect to MongoDB
-	client, err := db.ConnectMongoDB(cfg.MongoURI, cfg.ConnectionTimeout)
+	client, err := db.ConnectMongoDB(mongoConfig(cfg))
 	if err != nil {
 		log.Fatalf("Failed to connect to MongoDB: %v", err)
 	}
@@ -38,30 +62,279 @@ func main() {
 	// Get database handle
 	database := client.Database(cfg.DatabaseName)
 
+	// Run the selected command
+	switch cfg.Command {
+	case config.CommandPreflight:
+		runPreflight(database, cfg)
+	case config.CommandCleanup:
+		runCleanup(database, cfg)
+	case config.CommandSchedule:
+		runSchedule(database, cfg)
+	case config.CommandWatch:
+		runWatch(database, cfg)
+	case config.CommandRecheck:
+		return runRecheck(database, cfg)
+	default:
+		return runValidation(database, cfg)
+	}
+	return exitClean
+}
+
+func runValidation(database *mongo.Database, cfg *config.Configuration) int {
+	summary, err := validate(database, cfg, time.Now())
+	if err != nil {
+		log.Fatalf("Validation failed: %v", err)
+	}
+	return summary.ExitCode
+}
+
+// validate runs one validation pass over the documents recovered within -since of now, and returns its summary
+func validate(database *mongo.Database, cfg *config.Configuration, now time.Time) (models.RunSummary, error) {
+	startedAt := time.Now()
+
+	var since time.Time
+	if cfg.Since > 0 {
+		since = now.Add(-cfg.Since)
+		fmt.Printf("Validating documents recovered since %s\n", since.Format(time.RFC3339))
+	}
+
 	// Query event_recovery collection
-	eventRecoveries, err := db.GetEventRecoveries(database, cfg.CollectionName, cfg.DocLimit, cfg.QueryTimeout)
+	eventRecoveries, err := db.GetEventRecoveries(database, cfg.CollectionName, cfg.DocLimit, cfg.QueryTimeout, cfg.SkipVerified, since)
 	if err != nil {
-		log.Fatalf("Failed to get event recoveries: %v", err)
+		return models.RunSummary{}, fmt.Errorf("failed to get event recoveries: %v", err)
 	}
 
+	// Make sure destination lookups will not scan whole collections
+	if cfg.IndexCheck != "off" || cfg.CreateIndexes {
+		if err := checkDestinationIndexes(database, eventRecoveries, cfg); err != nil {
+			return models.RunSummary{}, err
+		}
+	}
+
+	return validateRecoveries(database, cfg, eventRecoveries, startedAt, since), nil
+}
+
+// validateRecoveries validates the given documents as one run, hands the results to the sinks and returns the run summary.
+// Nothing after the sinks are built may abort the run, failed destination checks are reported as event errors instead.
+func validateRecoveries(database *mongo.Database, cfg *config.Configuration, eventRecoveries []models.EventRecovery, startedAt time.Time, since time.Time) models.RunSummary {
+	// Identify this run in written-back statuses
+	runID := primitive.NewObjectID().Hex()
+	fmt.Printf("Run ID: %s\n", runID)
+
+	// Results go to every configured sink as they arrive
+	sinks := buildSinks(database, cfg)
+
 	// Process all documents
-	results := processAllDocuments(database, eventRecoveries, cfg)
+	results, duplicates := processAllDocuments(database, eventRecoveries, cfg, runID, sinks)
+
+	// Look up missing events again once the pipeline has had time to catch up
+	if len(cfg.RecheckDelays) > 0 {
+		recheckMissing(database, eventRecoveries, results, cfg, runID, sinks)
+	}
+
+	// Print the run summary
+	printRunSummary(results, cfg)
+
+	// Run the configured SQL destination checks
+	if len(cfg.SQLChecks) > 0 {
+		runSQLChecks(results, cfg)
+	}
+
+	// Check the same events in MySQL if configured
+	var combined []models.CombinedResult
+	if cfg.MySQLDSN != "" {
+		mysqlResults := checkAllEventsInMySQL(results, cfg)
+		combined = db.CombineResults(results, mysqlResults)
+	}
 
 	// Create report for missing data
-	report.CreateMissingDataReport(results)
+	missingReport := report.BuildMissingDataReport(results, combined, duplicates, report.Options{
+		TopUsers:          cfg.TopUsers,
+		PseudonymiseUUIDs: cfg.PseudonymiseUUIDs,
+		PseudonymSalt:     cfg.UUIDSalt,
+		Platforms:         cfg.Platforms,
+		EventTime:         cfg.EventTime,
+	})
+	summary := report.Summarise(runID, startedAt, len(eventRecoveries), results, missingReport)
+	if !since.IsZero() {
+		summary.Since = &since
+	}
+	summary.Status, summary.ExitCode = runStatus(summary, cfg)
+
+	// Hand the report to every sink, a failing sink never stops the others
+	if failed := sinks.Finish(&summary, missingReport); failed > 0 {
+		summary.Status, summary.ExitCode = models.RunSinkFailure, exitFailure
+	}
+
+	// Alert when the configured thresholds are crossed
+	if cfg.Alerts != nil {
+		sendRunAlerts(database, cfg, summary, missingReport)
+	}
+
+	fmt.Printf("Run finished: %s (exit code %d)\n", summary.Status, summary.ExitCode)
+
+	// The summary JSON comes last so wrappers can read it from the final line of stdout
+	if cfg.SummaryJSON != "" {
+		if err := writeSummaryJSON(cfg.SummaryJSON, summary); err != nil {
+			fmt.Printf("❌ Failed to write summary JSON: %v\n", err)
+			summary.ExitCode = exitFailure
+		}
+	}
+	return summary
+}
+
+// runStatus compares the run with the -max-errors and -max-missing thresholds, errors taking precedence
+func runStatus(summary models.RunSummary, cfg *config.Configuration) (string, int) {
+	if cfg.MaxErrors >= 0 && summary.Errors > cfg.MaxErrors {
+		return models.RunErrors, exitErrors
+	}
+	if cfg.MaxMissing >= 0 && summary.Missing > cfg.MaxMissing {
+		return models.RunMissing, exitMissing
+	}
+	return models.RunClean, exitClean
+}
+
+// writeSummaryJSON writes the summary as a single JSON object to path, or to stdout for "-"
+func writeSummaryJSON(path string, summary models.RunSummary) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// buildSinks creates the configured result sinks, the file sink always first so later sinks see the report path
+func buildSinks(database *mongo.Database, cfg *config.Configuration) *sink.Fanout {
+	var sinks []sink.ResultSink
+	for _, name := range cfg.Sinks {
+		switch name {
+		case "file":
+			sinks = append([]sink.ResultSink{&sink.FileSink{Dir: cfg.ReportDir}}, sinks...)
+		case "stdout":
+			sinks = append(sinks, sink.NewStdoutSink())
+		case "mongo":
+			sinks = append(sinks, &sink.MongoSink{
+				DB:                 database,
+				RunsCollection:     cfg.RunsCollection,
+				FindingsCollection: cfg.FindingsCollection,
+				Config:             config.Snapshot(),
+				TimeoutSec:         cfg.QueryTimeout,
+			})
+		case "webhook":
+			sinks = append(sinks, sink.NewWebhookSink(cfg.WebhookURL, cfg.WebhookTimeout))
+		}
+	}
+	return sink.NewFanout(sinks...)
+}
+
+func mongoConfig(cfg *config.Configuration) *db.MongoConfig {
+	mongoConfig := db.DefaultMongoConfig()
+	mongoConfig.URI = cfg.MongoURI
+	mongoConfig.Timeout = time.Duration(cfg.ConnectionTimeout) * time.Second
+	mongoConfig.TLSCAFile = cfg.TLSCAFile
+	mongoConfig.TLSCertFile = cfg.TLSCertFile
+	mongoConfig.TLSKeyFile = cfg.TLSKeyFile
+	mongoConfig.AuthMechanism = cfg.AuthMechanism
+	mongoConfig.AuthSource = cfg.AuthSource
+	mongoConfig.ReadPreference = cfg.ReadPreference
+	mongoConfig.MaxStaleness = cfg.MaxStaleness
+	mongoConfig.ReadConcern = cfg.ReadConcern
+	mongoConfig.Compressors = cfg.Compressors
+	mongoConfig.MinPoolSize = cfg.MinPoolSize
+	mongoConfig.MaxPoolSize = cfg.MaxPoolSize
+	mongoConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	return mongoConfig
 }
 
 func printConfiguration(cfg *config.Configuration) {
 	fmt.Printf("Configuration:\n")
+	fmt.Printf("  Command: %s\n", cfg.Command)
 	fmt.Printf("  MongoDB URI: %s\n", cfg.MongoURI)
 	fmt.Printf("  Database: %s\n", cfg.DatabaseName)
 	fmt.Printf("  Collection: %s\n", cfg.CollectionName)
 	fmt.Printf("  Query Timeout: %d seconds\n", cfg.QueryTimeout)
 	fmt.Printf("  Connection Timeout: %d seconds\n", cfg.ConnectionTimeout)
 	fmt.Printf("  Max Concurrent Operations: %d\n", cfg.MaxConcurrent)
+	fmt.Printf("  Pool Size: %d-%d (idle timeout %s)\n", cfg.MinPoolSize, cfg.MaxPoolSize, cfg.MaxConnIdleTime)
+	if cfg.ReadPreference != "" {
+		fmt.Printf("  Read Preference: %s", cfg.ReadPreference)
+		if cfg.MaxStaleness > 0 {
+			fmt.Printf(" (max staleness %s)", cfg.MaxStaleness)
+		}
+		fmt.Println()
+	}
+	if cfg.ReadConcern != "" {
+		fmt.Printf("  Read Concern: %s\n", cfg.ReadConcern)
+	}
+	if cfg.AuthMechanism != "" {
+		fmt.Printf("  Auth Mechanism: %s\n", cfg.AuthMechanism)
+	}
+	if cfg.TLSCAFile != "" || cfg.TLSCertFile != "" {
+		fmt.Printf("  TLS: CA %q, client certificate %q\n", cfg.TLSCAFile, cfg.TLSCertFile)
+	}
+	if len(cfg.Compressors) > 0 {
+		fmt.Printf("  Compressors: %v\n", cfg.Compressors)
+	}
 	fmt.Println("  GOMAXPROCS:", runtime.GOMAXPROCS(0))
 	fmt.Println("  NumCPU:", runtime.NumCPU())
 
+	fmt.Printf("  Top Users: %d\n", cfg.TopUsers)
+	if cfg.PseudonymiseUUIDs {
+		fmt.Printf("  Pseudonymise UUIDs: enabled\n")
+	}
+	if cfg.PlatformRegex != "" {
+		fmt.Printf("  Platform Regex: %s\n", cfg.PlatformRegex)
+	} else {
+		fmt.Printf("  Platform Separator: %q\n", cfg.PlatformSeparator)
+	}
+	fmt.Printf("  Event Time Source: %s\n", cfg.EventTimeSource)
+	if cfg.DeepCompare {
+		fmt.Printf("  Deep Compare: %s\n", cfg.CompareFields)
+	}
+
+	if cfg.MySQLDSN != "" {
+		fmt.Printf("  MySQL Checks: enabled (%d concurrent, batches of %d, %d seconds timeout)\n",
+			cfg.MySQLMaxConcurrent, cfg.MySQLBatchSize, cfg.MySQLQueryTimeout)
+		fmt.Printf("  MySQL Screens: %v\n", cfg.MySQLScreens)
+		fmt.Printf("  MySQL Time Window: %s\n", cfg.MySQLTimeWindow)
+	}
+	for _, check := range cfg.SQLChecks {
+		fmt.Printf("  SQL Check: %s (%s)\n", check.Name, check.Driver)
+	}
+
+	if cfg.WriteBack {
+		fmt.Printf("  Write Back: enabled\n")
+	}
+	fmt.Printf("  Sinks: %v\n", cfg.Sinks)
+	if cfg.HasSink("mongo") {
+		fmt.Printf("  Persist Results: %s, %s\n", cfg.RunsCollection, cfg.FindingsCollection)
+	}
+	if len(cfg.RecheckDelays) > 0 {
+		fmt.Printf("  Re-check Delays: %v\n", cfg.RecheckDelays)
+	}
+	if cfg.Since > 0 {
+		fmt.Printf("  Since: %s\n", cfg.Since)
+	}
+	if cfg.Command == config.CommandWatch {
+		fmt.Printf("  Settle Delay: %s (resume tokens in %s)\n", cfg.SettleDelay, cfg.ResumeCollection)
+	}
+	if cfg.Command == config.CommandSchedule {
+		fmt.Printf("  Schedule: %q (history of %d runs)\n", cfg.Cron, cfg.ScheduleHistory)
+	}
+	fmt.Printf("  Exit Thresholds: %d missing, %d errors\n", cfg.MaxMissing, cfg.MaxErrors)
+	if cfg.Alerts != nil {
+		fmt.Printf("  Alert Rules: %d (%s format)\n", len(cfg.Alerts.Rules), cfg.Alerts.Format)
+	}
+	if cfg.SkipVerified {
+		fmt.Printf("  Skip Verified: enabled\n")
+	}
+
 	if cfg.DocLimit > 0 {
 		fmt.Printf("  Document Limit: %d\n", cfg.DocLimit)
 	} else {
@@ -69,16 +342,232 @@ func printConfiguration(cfg *config.Configuration) {
 	}
 }
 
-func processAllDocuments(db *mongo.Database, eventRecoveries []models.EventRecovery, cfg *config.Configuration) []models.Result {
+func checkDestinationIndexes(database *mongo.Database, eventRecoveries []models.EventRecovery, cfg *config.Configuration) error {
+	// Pick one sample event ID for every destination collection the run will touch
+	sampleIDs := make(map[string]string)
+	for _, recovery := range eventRecoveries {
+		for _, event := range recovery.Events {
+			if event.EntityType == "" {
+				continue
+			}
+			if _, ok := sampleIDs[event.EntityType]; !ok {
+				sampleIDs[event.EntityType] = event.ID
+			}
+		}
+	}
+
+	fmt.Printf("Checking indexes on %d destination collections\n", len(sampleIDs))
+	checks := db.CheckDestinationIndexes(database, sampleIDs, cfg.QueryTimeout, cfg.CreateIndexes)
+
+	var scans []string
+	for _, check := range checks {
+		switch {
+		case check.Error != nil:
+			fmt.Printf("⚠️ Could not check indexes on %s: %v\n", check.Collection, check.Error)
+		case check.CollectionScan:
+			fmt.Printf("⚠️ Lookups on %s use a COLLSCAN plan (indexes: %v)\n", check.Collection, check.Indexes)
+			scans = append(scans, check.Collection)
+		case !check.HasIndex:
+			fmt.Printf("⚠️ %s has no index leading with %s (plan: %v)\n", check.Collection, db.MappingIDField, check.PlanStages)
+		case check.Created:
+			fmt.Printf("✅ %s now uses index %s\n", check.Collection, check.IndexName)
+		default:
+			fmt.Printf("✅ %s uses index %s\n", check.Collection, check.IndexName)
+		}
+	}
+
+	if len(scans) > 0 && cfg.IndexCheck == "strict" {
+		return fmt.Errorf("refusing to run: lookups on %v would scan whole collections (use -create-indexes or -index-check=warn)", scans)
+	}
+	return nil
+}
+
+func printRunSummary(results []models.Result, cfg *config.Configuration) {
+	platforms := report.PlatformBreakdown(results, cfg.Platforms)
+
+	var total models.PlatformStats
+	for _, stats := range platforms {
+		total.Found += stats.Found
+		total.Missing += stats.Missing
+		total.Errors += stats.Errors
+	}
+
+	fmt.Printf("Run summary: %d events found, %d events not found, %d errors\n", total.Found, total.Missing, total.Errors)
+	report.PrintPlatformBreakdown(platforms)
+}
+
+func processAllDocuments(database *mongo.Database, eventRecoveries []models.EventRecovery, cfg *config.Configuration, runID string, sinks *sink.Fanout) ([]models.Result, []models.DuplicateEvent) {
 	// Collect all results from all documents
 	var allResults []models.Result
+	resultsByID := make(map[string]models.Result)
+
+	// Index event IDs so each unique event is validated only once
+	eventIndex := validator.NewEventIndex()
 
 	// Process each document
 	for docIndex, recovery := range eventRecoveries {
-		fmt.Printf("Processing document %d with %d events\n", docIndex+1, len(recovery.Events))
-		results := validator.ProcessEventsInDocument(db, recovery.Events, cfg.QueryTimeout, cfg.MaxConcurrent, docIndex+1)
+		events := eventIndex.Add(recovery, docIndex+1)
+		if skipped := len(recovery.Events) - len(events); skipped > 0 {
+			fmt.Printf("Processing document %d with %d events (%d duplicate events skipped)\n", docIndex+1, len(events), skipped)
+		} else {
+			fmt.Printf("Processing document %d with %d events\n", docIndex+1, len(events))
+		}
+		results := validator.ProcessEventsInDocument(database, events, cfg.QueryTimeout, cfg.MaxConcurrent, docIndex+1, cfg.FieldPairs)
 		allResults = append(allResults, results...)
+
+		// Events waiting for a re-check reach the sinks and the document once their final result is known
+		if len(cfg.RecheckDelays) > 0 {
+			sinks.Write(runID, settledResults(results))
+			continue
+		}
+		sinks.Write(runID, results)
+
+		for _, result := range results {
+			resultsByID[result.EventID] = result
+		}
+
+		// Record the outcome on the recovery document itself
+		if cfg.WriteBack {
+			writeValidationStatus(database, recovery, resultsByID, cfg, runID)
+		}
 	}
 
-	return allResults
+	duplicates := eventIndex.Duplicates()
+	if len(duplicates) > 0 {
+		fmt.Printf("Found %d event IDs that appear more than once\n", len(duplicates))
+	}
+
+	return allResults, duplicates
+}
+
+// settledResults leaves out the results of events that will be re-checked
+func settledResults(results []models.Result) []models.Result {
+	var settled []models.Result
+	for _, result := range results {
+		if result.Error != nil || result.FoundInDest {
+			settled = append(settled, result)
+		}
+	}
+	return settled
+}
+
+// recheckMissing re-checks the events that were not found, then hands their final results to the sinks
+// and writes back the status of every document, which processAllDocuments left until now
+func recheckMissing(database *mongo.Database, eventRecoveries []models.EventRecovery, results []models.Result, cfg *config.Configuration, runID string, sinks *sink.Fanout) {
+	rechecked := validator.RecheckMissing(database, results, cfg.RecheckDelays, cfg.QueryTimeout, cfg.MaxConcurrent, cfg.FieldPairs)
+	sinks.Write(runID, rechecked)
+
+	if cfg.WriteBack {
+		resultsByID := make(map[string]models.Result, len(results))
+		for _, result := range results {
+			resultsByID[result.EventID] = result
+		}
+		for _, recovery := range eventRecoveries {
+			writeValidationStatus(database, recovery, resultsByID, cfg, runID)
+		}
+	}
+}
+
+// writeValidationStatus records the outcome of a document's events on the recovery document itself
+func writeValidationStatus(database *mongo.Database, recovery models.EventRecovery, resultsByID map[string]models.Result, cfg *config.Configuration, runID string) {
+	status := validator.BuildValidationStatus(recovery, resultsByID, runID)
+	if err := db.WriteValidationStatus(database, cfg.CollectionName, recovery.ID, status, cfg.QueryTimeout); err != nil {
+		fmt.Printf("Failed to write validation status for document %s: %v\n", recovery.ID.Hex(), err)
+	}
+}
+
+func mysqlConfig(cfg *config.Configuration) *db.MySQLConfig {
+	mysqlConfig := db.DefultMySQLConfig()
+	mysqlConfig.DSN = cfg.MySQLDSN
+	mysqlConfig.MaxOpenConns = cfg.MySQLMaxOpenConns
+	mysqlConfig.MaxIdleConns = cfg.MySQLMaxIdleConns
+	mysqlConfig.ConnMaxLifetime = cfg.MySQLConnMaxLifetime
+	mysqlConfig.QueryTimeout = time.Duration(cfg.MySQLQueryTimeout) * time.Second
+	mysqlConfig.MaxConcurrent = cfg.MySQLMaxConcurrent
+	mysqlConfig.BatchSize = cfg.MySQLBatchSize
+	mysqlConfig.ScreenColumns = cfg.MySQLScreens.Columns()
+	mysqlConfig.TimeWindow = cfg.MySQLTimeWindow
+	if cfg.EventTime != nil {
+		mysqlConfig.EventTime = cfg.EventTime
+	}
+	return mysqlConfig
+}
+
+func checkAllEventsInMySQL(results []models.Result, cfg *config.Configuration) []*models.MySQLEventResult {
+	mysqlConfig := mysqlConfig(cfg)
+
+	events := make([]models.Event, 0, len(results))
+	for _, result := range results {
+		events = append(events, result.Event)
+	}
+
+	// An unreachable MySQL errors the events it should have checked instead of failing the run
+	var mysqlResults []*models.MySQLEventResult
+	mysqlDB, err := db.ConnectMySQL(mysqlConfig)
+	if err != nil {
+		fmt.Printf("❌ Failed to connect to MySQL: %v\n", err)
+		mysqlResults = db.FailEventsInMySQL(events, db.DefaultEventCollectionMap(), cfg.MySQLScreens, mysqlConfig,
+			fmt.Errorf("failed to connect to MySQL: %v", err))
+	} else {
+		defer mysqlDB.Close()
+		mysqlResults = db.CheckEventsInMySQL(mysqlDB, events, db.DefaultEventCollectionMap(), cfg.MySQLScreens, mysqlConfig)
+	}
+
+	var found, notFound, unexpected, suspicious, errored int
+	for _, result := range mysqlResults {
+		if result.Error != nil {
+			errored++
+		} else if result.Found {
+			found++
+		} else if result.Outcome == models.MySQLUnexpectedScreen {
+			unexpected++
+		} else if result.Outcome == models.MySQLSuspicious {
+			suspicious++
+		} else {
+			notFound++
+		}
+	}
+	fmt.Printf("MySQL summary: %d events found, %d found with unexpected screen, %d outside the time window, %d events not found, %d errors\n",
+		found, unexpected, suspicious, notFound, errored)
+
+	return mysqlResults
+}
+
+func runSQLChecks(results []models.Result, cfg *config.Configuration) {
+	events := make([]models.Event, 0, len(results))
+	for _, result := range results {
+		events = append(events, result.Event)
+	}
+
+	for _, check := range cfg.SQLChecks {
+		// A check that cannot be set up errors the events it applies to, the other checks still run
+		var sqlResults []*models.SQLCheckResult
+		checker, err := db.NewSQLChecker(check)
+		if err != nil {
+			fmt.Printf("❌ Failed to set up SQL check %s: %v\n", check.Name, err)
+			sqlResults = check.FailEvents(events, fmt.Errorf("failed to set up SQL check %s: %v", check.Name, err))
+		} else {
+			sqlResults = checker.CheckEvents(events)
+			checker.Close()
+		}
+
+		var found, notFound, errored int
+		for i, sqlResult := range sqlResults {
+			if sqlResult == nil {
+				continue
+			}
+
+			if sqlResult.Error != nil {
+				errored++
+			} else if sqlResult.Found {
+				found++
+			} else {
+				notFound++
+			}
+
+			results[i].SQLChecks = append(results[i].SQLChecks, *sqlResult)
+		}
+
+		fmt.Printf("SQL check %s summary: %d events found, %d events not found, %d errors\n", check.Name, found, notFound, errored)
+	}
 }
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// Original types recorded for an entity code
+const (
+	EntityCodeString  = "string"
+	EntityCodeInt32   = "int32"
+	EntityCodeInt64   = "int64"
+	EntityCodeDouble  = "double"
+	EntityCodeNull    = "null"
+	EntityCodeMissing = "missing"
+	EntityCodeOther   = "other"
+)
+
+// EntityCode is the polymorphic entity_code field. Clients send it as a string,
+// int32, int64 or double; Value holds the canonical form used for grouping,
+// comparison and reports, Type the BSON type it was stored as.
+type EntityCode struct {
+	Value string
+	Type  string
+	raw   bson.RawValue // Original value, written back unchanged
+}
+
+// NewEntityCode builds an entity code from a decoded Go value
+func NewEntityCode(v interface{}) EntityCode {
+	if v == nil {
+		return EntityCode{Type: EntityCodeNull}
+	}
+	bsonType, data, err := bson.MarshalValue(v)
+	if err != nil {
+		return EntityCode{Value: fmt.Sprint(v), Type: EntityCodeOther}
+	}
+	return newEntityCodeFromRaw(bson.RawValue{Type: bsonType, Value: data})
+}
+
+// newEntityCodeFromRaw normalises a raw BSON value
+func newEntityCodeFromRaw(raw bson.RawValue) EntityCode {
+	code := EntityCode{raw: raw}
+
+	switch raw.Type {
+	case bson.TypeString:
+		code.Type = EntityCodeString
+		code.Value = canonicalString(raw.StringValue())
+	case bson.TypeInt32:
+		code.Type = EntityCodeInt32
+		code.Value = strconv.FormatInt(int64(raw.Int32()), 10)
+	case bson.TypeInt64:
+		code.Type = EntityCodeInt64
+		code.Value = strconv.FormatInt(raw.Int64(), 10)
+	case bson.TypeDouble:
+		code.Type = EntityCodeDouble
+		code.Value = canonicalFloat(raw.Double())
+	case bson.TypeNull, bson.TypeUndefined:
+		code.Type = EntityCodeNull
+	default:
+		code.Type = EntityCodeOther
+		code.Value = raw.String()
+	}
+
+	return code
+}
+
+// canonicalString trims a string code and drops leading zeros from integer codes
+func canonicalString(s string) string {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return strconv.FormatInt(n, 10)
+	}
+	return s
+}
+
+// canonicalFloat formats integral doubles like integers
+func canonicalFloat(f float64) string {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// OriginalType returns the BSON type the code was stored as, "missing" if the field was absent
+func (c EntityCode) OriginalType() string {
+	if c.Type == "" {
+		return EntityCodeMissing
+	}
+	return c.Type
+}
+
+// String returns the canonical representation
+func (c EntityCode) String() string {
+	return c.Value
+}
+
+// UnmarshalBSONValue implements bson.ValueUnmarshaler
+func (c *EntityCode) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	// The decoder may reuse its buffer, keep our own copy
+	value := make([]byte, len(data))
+	copy(value, data)
+
+	*c = newEntityCodeFromRaw(bson.RawValue{Type: t, Value: value})
+	return nil
+}
+
+// MarshalBSONValue implements bson.ValueMarshaler, writing back the original value.
+// Codes that were absent or built without a raw value are written as null.
+func (c EntityCode) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if c.raw.Type == 0 {
+		return bson.TypeNull, nil, nil
+	}
+	return c.raw.Type, c.raw.Value, nil
+}
+
+// MarshalJSON emits the canonical representation, or null for null and absent codes
+func (c EntityCode) MarshalJSON() ([]byte, error) {
+	if c.Type == EntityCodeNull || c.Type == "" {
+		return []byte("null"), nil
+	}
+	return json.Marshal(c.Value)
+}
+
+// UnmarshalJSON accepts both string and numeric codes, e.g. from a saved report
+func (c *EntityCode) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*c = NewEntityCode(v)
+	return nil
+}
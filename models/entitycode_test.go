@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestEntityCodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		doc       bson.D
+		value     string
+		bsonType  string // Type after decoding
+		roundType string // Type after encoding and decoding again
+		json      string // JSON encoding of the code
+		jsonType  string // Type after decoding the JSON encoding
+	}{
+		{"string", bson.D{{Key: "entity_code", Value: " 0042 "}}, "42", EntityCodeString, EntityCodeString, `"42"`, EntityCodeString},
+		{"int32", bson.D{{Key: "entity_code", Value: int32(42)}}, "42", EntityCodeInt32, EntityCodeInt32, `"42"`, EntityCodeString},
+		{"int64", bson.D{{Key: "entity_code", Value: int64(42)}}, "42", EntityCodeInt64, EntityCodeInt64, `"42"`, EntityCodeString},
+		{"double", bson.D{{Key: "entity_code", Value: 42.0}}, "42", EntityCodeDouble, EntityCodeDouble, `"42"`, EntityCodeString},
+		{"fractional double", bson.D{{Key: "entity_code", Value: 42.5}}, "42.5", EntityCodeDouble, EntityCodeDouble, `"42.5"`, EntityCodeString},
+		{"null", bson.D{{Key: "entity_code", Value: nil}}, "", EntityCodeNull, EntityCodeNull, `null`, EntityCodeNull},
+		{"missing", bson.D{}, "", EntityCodeMissing, EntityCodeNull, `null`, EntityCodeNull},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := bson.Marshal(tt.doc)
+			if err != nil {
+				t.Fatalf("marshal source document: %v", err)
+			}
+
+			var event Event
+			if err := bson.Unmarshal(data, &event); err != nil {
+				t.Fatalf("unmarshal BSON: %v", err)
+			}
+			if event.EntityCode.Value != tt.value || event.EntityCode.OriginalType() != tt.bsonType {
+				t.Fatalf("decoded %q (%s), want %q (%s)", event.EntityCode.Value, event.EntityCode.OriginalType(), tt.value, tt.bsonType)
+			}
+
+			// BSON round trip keeps the original value
+			encoded, err := bson.Marshal(event)
+			if err != nil {
+				t.Fatalf("marshal BSON: %v", err)
+			}
+			var decoded Event
+			if err := bson.Unmarshal(encoded, &decoded); err != nil {
+				t.Fatalf("unmarshal round trip: %v", err)
+			}
+			if decoded.EntityCode.Value != tt.value || decoded.EntityCode.OriginalType() != tt.roundType {
+				t.Fatalf("BSON round trip gave %q (%s), want %q (%s)", decoded.EntityCode.Value, decoded.EntityCode.OriginalType(), tt.value, tt.roundType)
+			}
+
+			// JSON keeps the canonical value, null and absent codes stay null
+			codeJSON, err := json.Marshal(event.EntityCode)
+			if err != nil {
+				t.Fatalf("marshal code JSON: %v", err)
+			}
+			if string(codeJSON) != tt.json {
+				t.Fatalf("JSON encoding gave %s, want %s", codeJSON, tt.json)
+			}
+			jsonData, err := json.Marshal(MissingEvent{EntityCode: event.EntityCode})
+			if err != nil {
+				t.Fatalf("marshal JSON: %v", err)
+			}
+			var missingEvent MissingEvent
+			if err := json.Unmarshal(jsonData, &missingEvent); err != nil {
+				t.Fatalf("unmarshal JSON: %v", err)
+			}
+			if missingEvent.EntityCode.Value != tt.value || missingEvent.EntityCode.OriginalType() != tt.jsonType {
+				t.Fatalf("JSON round trip gave %q (%s), want %q (%s)", missingEvent.EntityCode.Value, missingEvent.EntityCode.OriginalType(), tt.value, tt.jsonType)
+			}
+
+			// A finding carrying the event must be storable
+			if _, err := bson.Marshal(Finding{Detail: missingEvent}); err != nil {
+				t.Fatalf("marshal finding: %v", err)
+			}
+		})
+	}
+}
@@ -7,18 +7,61 @@ import (
 
 // Event represents an event in the event array
 type Event struct {
-	ID         string      `bson:"id" json:"id"`
-	EntityType string      `bson:"entity_type" json:"entity_type"`
-	EntityCode interface{} `bson:"entity_code" json:"entity_code"`
-	EventName  string      `bson:"event_name" json:"event_name"`
-	UUID       string      `bson:"uuid" json:"uuid"`
-	SessionID  string      `bson:"session_id" json:"session_id"`
+	ID         string     `bson:"id" json:"id"`
+	EntityType string     `bson:"entity_type" json:"entity_type"`
+	EntityCode EntityCode `bson:"entity_code" json:"entity_code"`
+	EventName  string     `bson:"event_name" json:"event_name"`
+	UUID       string     `bson:"uuid" json:"uuid"`
+	SessionID  string     `bson:"session_id" json:"session_id"`
+}
+
+// FieldValue returns the value of the event field with the given bson name
+func (e Event) FieldValue(name string) (interface{}, bool) {
+	switch name {
+	case "id":
+		return e.ID, true
+	case "entity_type":
+		return e.EntityType, true
+	case "entity_code":
+		return e.EntityCode.Value, true
+	case "event_name":
+		return e.EventName, true
+	case "uuid":
+		return e.UUID, true
+	case "session_id":
+		return e.SessionID, true
+	}
+	return nil, false
 }
 
 // EventRecovery represents a document in the new_event_recovery collection
 type EventRecovery struct {
-	ID     primitive.ObjectID `bson:"_id,omitempty"`
-	Events []Event            `bson:"event"`
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	Events     []Event            `bson:"event"`
+	Validation *ValidationStatus  `bson:"validation,omitempty"`
+}
+
+// Per-event validation statuses written back onto recovery documents
+const (
+	EventStatusFound   = "found"
+	EventStatusMissing = "missing"
+	EventStatusError   = "error"
+)
+
+// ValidationStatus is written back onto a recovery document once it has been processed
+type ValidationStatus struct {
+	RunID     string        `bson:"run_id" json:"run_id"`
+	CheckedAt time.Time     `bson:"checked_at" json:"checked_at"`
+	Found     int           `bson:"found" json:"found"`
+	Missing   int           `bson:"missing" json:"missing"`
+	Errors    int           `bson:"errors" json:"errors"`
+	Events    []EventStatus `bson:"events" json:"events"`
+}
+
+// EventStatus stores the validation status of one event in a recovery document
+type EventStatus struct {
+	ID     string `bson:"id" json:"id"`
+	Status string `bson:"status" json:"status"`
 }
 
 // Result represents the validation result for a single event
@@ -30,6 +73,45 @@ type Result struct {
 	Error          error
 	Event          Event // Store the entire event for missing data export
 	OffsetID       int
+	Mismatches     []FieldMismatch  // Populated only in deep-compare mode
+	SQLChecks      []SQLCheckResult // Results of the configured SQL destination checks
+	ResolvedAfter  time.Duration    // Delay of the re-check that found the event, zero if found on the first pass
+}
+
+// SQLCheckResult represents the result of a configured SQL destination check for one event
+type SQLCheckResult struct {
+	Check     string
+	EventID   string
+	EventName string
+	Found     bool
+	Evidence  map[string]string // Evidence field -> value from the matched row
+	Error     error
+}
+
+// FieldPair maps a recovery event field to a field path in the destination document
+type FieldPair struct {
+	Source string // bson field name on Event, e.g. event_name
+	Dest   string // dotted path in the destination document, e.g. event.eventName
+}
+
+// FieldMismatch records a destination field whose value differs from the recovered event
+type FieldMismatch struct {
+	Field       string      `json:"field"`
+	DestField   string      `json:"dest_field"`
+	SourceValue interface{} `json:"source_value"`
+	DestValue   interface{} `json:"dest_value"`
+}
+
+// IndexCheck stores whether lookups on a destination collection can use an index
+type IndexCheck struct {
+	Collection     string
+	Indexes        []string // Names of all indexes on the collection
+	HasIndex       bool     // An index leads with event.mappingId
+	IndexName      string
+	PlanStages     []string // Stages of the explained sample lookup, outermost first
+	CollectionScan bool
+	Created        bool // The index was created by this run
+	Error          error
 }
 
 // MySQLEvent represents a row from the app_tracking_new table
@@ -43,17 +125,33 @@ type MySQLEvent struct {
 	SessionStartTime time.Time
 	SessionEndTime   time.Time
 	DateOfCreation   time.Time
+	Screens          map[string]string // Configured screen column -> value
 }
 
+// MySQL check outcomes
+const (
+	MySQLFound            = "found"
+	MySQLNotFound         = "not_found"
+	MySQLUnexpectedScreen = "found_unexpected_screen"
+	MySQLSuspicious       = "found_out_of_window"
+	MySQLError            = "error"
+)
+
 // MySQLEventResult represents the result of a MySQL event check
 type MySQLEventResult struct {
-	EventID        string
-	EventName      string
-	CollectionName string
-	SessionID      string
-	Found          bool
-	Error          error
-	MySQLEvent     *MySQLEvent
+	EventID         string
+	EventName       string
+	CollectionName  string
+	SessionID       string
+	ProductType     int
+	Found           bool              // Found with an expected screen
+	Outcome         string            // One of the MySQL* outcome constants
+	ExpectedScreens map[string]string // Screen column -> expected screen
+	MatchedScreens  map[string]string // Screen column -> value in the matched row
+	EventTime       time.Time         // Event time derived from the event ID, zero if unknown
+	TimeDelta       time.Duration     // Distance between the event time and the matched row's session
+	Error           error
+	MySQLEvent      *MySQLEvent
 }
 
 // CombinedResult combines MongoDB and MySQL results
@@ -62,25 +160,241 @@ type CombinedResult struct {
 	MySQLResult *MySQLEventResult
 }
 
+// Mongo/MySQL consistency cases
+const (
+	ConsistencyBoth      = "in_both"
+	ConsistencyMongoOnly = "mongo_only"
+	ConsistencyMySQLOnly = "mysql_only"
+	ConsistencyNeither   = "neither"
+)
+
+// Consistency classifies the event by the stores it was found in. It returns an
+// empty string if the event was not checked in MySQL or a check errored.
+func (c CombinedResult) Consistency() string {
+	if c.MySQLResult == nil || c.MySQLResult.Error != nil || c.MongoResult.Error != nil {
+		return ""
+	}
+
+	switch {
+	case c.MongoResult.FoundInDest && c.MySQLResult.Found:
+		return ConsistencyBoth
+	case c.MongoResult.FoundInDest:
+		return ConsistencyMongoOnly
+	case c.MySQLResult.Found:
+		return ConsistencyMySQLOnly
+	default:
+		return ConsistencyNeither
+	}
+}
+
+// RunSummary stores the headline numbers of a validation run
+type RunSummary struct {
+	RunID             string     `bson:"run_id" json:"run_id"`
+	StartedAt         time.Time  `bson:"started_at" json:"started_at"`
+	FinishedAt        time.Time  `bson:"finished_at" json:"finished_at"`
+	Since             *time.Time `bson:"since,omitempty" json:"since,omitempty"` // Start of the -since window, nil for a full scan
+	Documents         int        `bson:"documents" json:"documents"`
+	Events            int        `bson:"events" json:"events"` // Unique events validated
+	Found             int        `bson:"found" json:"found"`
+	Missing           int        `bson:"missing" json:"missing"`
+	Errors            int        `bson:"errors" json:"errors"` // Distinct errors from MongoDB, MySQL and SQL checks
+	Duplicates        int        `bson:"duplicates" json:"duplicates"`
+	Mismatches        int        `bson:"mismatches" json:"mismatches"`
+	MySQLMissing      int        `bson:"mysql_missing" json:"mysql_missing"`
+	ResolvedByRecheck int        `bson:"resolved_by_recheck" json:"resolved_by_recheck"`
+	ReportPath        string     `bson:"report_path,omitempty" json:"report_path,omitempty"`
+	Status            string     `bson:"status" json:"status"`
+	ExitCode          int        `bson:"exit_code" json:"exit_code"`
+}
+
+// Run statuses, each with its own exit code
+const (
+	RunClean       = "clean"
+	RunMissing     = "missing_above_threshold"
+	RunErrors      = "errors_above_threshold"
+	RunSinkFailure = "sink_failure"
+)
+
+// ValidationRun is the document stored for each run in the validation_runs collection
+type ValidationRun struct {
+	RunID   string            `bson:"_id"`
+	Summary RunSummary        `bson:"summary"`
+	Config  map[string]string `bson:"config"` // Flag values with secrets redacted
+}
+
+// Finding types stored in the validation_findings collection
+const (
+	FindingMissing      = "missing"
+	FindingMySQLMissing = "mysql_missing"
+	FindingDuplicate    = "duplicate"
+	FindingError        = "error"
+)
+
+// Finding is a single missing, duplicate or error record of a run
+type Finding struct {
+	RunID      string      `bson:"run_id" json:"run_id"`
+	Type       string      `bson:"type" json:"type"`
+	Collection string      `bson:"collection,omitempty" json:"collection,omitempty"`
+	EventID    string      `bson:"event_id,omitempty" json:"event_id,omitempty"`
+	Message    string      `bson:"message,omitempty" json:"message,omitempty"`
+	Detail     interface{} `bson:"detail,omitempty" json:"detail,omitempty"`
+	CreatedAt  time.Time   `bson:"created_at" json:"created_at"`
+}
+
 // MissingDataReport stores information about missing events
 type MissingDataReport struct {
-	Timestamp         string                    `json:"timestamp"`
-	TotalCount        int                       `json:"total_count"`
-	ByCollection      map[string][]MissingEvent `json:"by_collection"`
-	MySQLMissingCount int                       `json:"mysql_missing_count"`
-	MySQLMissing      []MySQLMissingEvent       `json:"mysql_missing_events"`
-	Errors            []string                  `json:"errors,omitempty"` // Track errors
+	Timestamp         string                       `json:"timestamp"`
+	TotalCount        int                          `json:"total_count"`
+	ByCollection      map[string][]MissingEvent    `json:"by_collection"`
+	CheckedCount      map[string]int               `json:"checked_by_collection,omitempty"` // Events checked per collection
+	ResolvedByRecheck int                          `json:"resolved_by_recheck,omitempty"`   // Events missing on the first pass but found by a re-check
+	ResolvedByDelay   map[string]int               `json:"resolved_by_recheck_delay,omitempty"`
+	MySQLMissingCount int                          `json:"mysql_missing_count"`
+	MySQLMissing      []MySQLMissingEvent          `json:"mysql_missing_events"`
+	MySQLUnexpected   []MySQLUnexpectedEvent       `json:"mysql_unexpected_screen_events,omitempty"`
+	MySQLSuspicious   []MySQLSuspiciousEvent       `json:"mysql_suspicious_matches,omitempty"`
+	Consistency       *ConsistencyReport           `json:"consistency,omitempty"`
+	SQLChecks         map[string]*SQLCheckSummary  `json:"sql_checks,omitempty"`
+	Errors            []string                     `json:"errors,omitempty"` // Track errors
+	MismatchCount     int                          `json:"mismatch_count,omitempty"`
+	FieldMismatches   map[string][]MismatchedEvent `json:"field_mismatches,omitempty"`
+	DuplicateCount    int                          `json:"duplicate_count,omitempty"`
+	Duplicates        []DuplicateEvent             `json:"duplicates,omitempty"`
+	Sessions          *SessionReport               `json:"sessions,omitempty"`
+	Users             *UserReport                  `json:"users,omitempty"`
+	Platforms         map[string]PlatformStats     `json:"platforms,omitempty"`
+	Timeline          *TimelineReport              `json:"timeline,omitempty"`
+	DataQuality       *DataQualityReport           `json:"data_quality,omitempty"`
+}
+
+// RecheckReport stores the outcome of re-validating the events of an earlier report.
+// Still missing events are under by_collection, so a recheck report can itself be re-checked.
+type RecheckReport struct {
+	Timestamp    string                    `json:"timestamp"`
+	SourceReport string                    `json:"source_report"`
+	Checked      int                       `json:"checked"`
+	PresentCount int                       `json:"present_count"`
+	TotalCount   int                       `json:"total_count"` // Events still missing
+	Present      map[string][]MissingEvent `json:"present_by_collection"`
+	ByCollection map[string][]MissingEvent `json:"by_collection"`
+	SkippedCount int                       `json:"skipped_count"` // Source errors naming no event to re-check, carried over to errors
+	Errors       []string                  `json:"errors,omitempty"`
 }
 
 // MissingEvent stores information about a single missing event
 type MissingEvent struct {
-	ID         string      `json:"id"`
-	EntityType string      `json:"entity_type"`
-	EntityCode interface{} `json:"entity_code"`
-	EventName  string      `json:"event_name"`
-	UUID       string      `json:"uuid"`
-	SessionID  string      `json:"session_id"`
-	OffsetID   int         `json:"offset_id"`
+	ID         string     `json:"id"`
+	EntityType string     `json:"entity_type"`
+	EntityCode EntityCode `json:"entity_code"`
+	EventName  string     `json:"event_name"`
+	UUID       string     `json:"uuid"`
+	SessionID  string     `json:"session_id"`
+	OffsetID   int        `json:"offset_id"`
+	EventTime  string     `json:"event_time,omitempty"`
+}
+
+// MismatchedEvent stores an event that was found in its destination with differing fields
+type MismatchedEvent struct {
+	ID         string          `json:"id"`
+	EventName  string          `json:"event_name"`
+	SessionID  string          `json:"session_id"`
+	OffsetID   int             `json:"offset_id"`
+	Mismatches []FieldMismatch `json:"mismatches"`
+}
+
+// DuplicateEvent stores an event ID that appears more than once in the recovery collection
+type DuplicateEvent struct {
+	ID          string            `json:"id"`
+	EntityType  string            `json:"entity_type"`
+	EventName   string            `json:"event_name"`
+	Count       int               `json:"count"`
+	Identical   bool              `json:"identical"` // Whether every copy has the same payload
+	Occurrences []EventOccurrence `json:"occurrences"`
+}
+
+// EventOccurrence identifies where a copy of an event was found
+type EventOccurrence struct {
+	DocumentID string `json:"document_id"`
+	OffsetID   int    `json:"offset_id"`
+	Position   int    `json:"position"` // Index within the document's event array
+}
+
+// Session completeness statuses
+const (
+	SessionComplete      = "complete"
+	SessionPartiallyLost = "partially_lost"
+	SessionFullyLost     = "fully_lost"
+	SessionUnverified    = "unverified" // Nothing missing, but some lookups failed
+)
+
+// SessionReport classifies every recovered session by how many of its events reached the destination
+type SessionReport struct {
+	Complete      int              `json:"complete"`
+	PartiallyLost int              `json:"partially_lost"`
+	FullyLost     int              `json:"fully_lost"`
+	Unverified    int              `json:"unverified"`
+	Sessions      []SessionSummary `json:"sessions"` // Worst sessions first
+}
+
+// SessionSummary stores the validation counts for a single session
+type SessionSummary struct {
+	SessionID string `json:"session_id"`
+	Status    string `json:"status"`
+	Recovered int    `json:"recovered"`
+	Present   int    `json:"present"`
+	Missing   int    `json:"missing"`
+	Errors    int    `json:"errors,omitempty"`
+}
+
+// UserReport aggregates missing events by user UUID
+type UserReport struct {
+	AffectedUsers int          `json:"affected_users"`
+	Pseudonymised bool         `json:"pseudonymised"`
+	TopN          int          `json:"top_n"`
+	TopShare      float64      `json:"top_share_percent"` // Share of all missing events caused by the top-N users
+	TopUsers      []UserImpact `json:"top_users"`
+	Users         []UserImpact `json:"all_users"` // Most affected users first
+}
+
+// UserImpact stores the missing events attributed to a single user
+type UserImpact struct {
+	UUID         string   `json:"uuid"`
+	MissingCount int      `json:"missing_count"`
+	SessionCount int      `json:"session_count"`
+	Sessions     []string `json:"sessions"`
+	Collections  []string `json:"collections"`
+	EventNames   []string `json:"event_names"`
+}
+
+// PlatformStats stores the validation counts for a single client platform
+type PlatformStats struct {
+	Total   int `json:"total"`
+	Found   int `json:"found"`
+	Missing int `json:"missing"`
+	Errors  int `json:"errors"`
+}
+
+// TimelineReport places missing events in time using the time derived from each event
+type TimelineReport struct {
+	FirstSeen    *time.Time                     `json:"first_seen,omitempty"`
+	LastSeen     *time.Time                     `json:"last_seen,omitempty"`
+	Undated      int                            `json:"undated"` // Missing events without a usable time
+	ByCollection map[string]*CollectionTimeline `json:"by_collection"`
+}
+
+// CollectionTimeline stores the missing event histograms for a single collection
+type CollectionTimeline struct {
+	FirstSeen time.Time      `json:"first_seen"`
+	LastSeen  time.Time      `json:"last_seen"`
+	Hourly    map[string]int `json:"hourly"`
+	Daily     map[string]int `json:"daily"`
+}
+
+// DataQualityReport stores data-quality findings about the recovered events
+type DataQualityReport struct {
+	EntityCodeTypes         map[string]int            `json:"entity_code_types"`                   // Original BSON type -> event count
+	InconsistentEntityCodes map[string]map[string]int `json:"inconsistent_entity_codes,omitempty"` // Collection -> type counts, collections with mixed types only
+	Findings                []string                  `json:"findings,omitempty"`
 }
 
 // MySQLMissingEvent stores information about a missing MySQL event
@@ -92,3 +406,63 @@ type MySQLMissingEvent struct {
 	ProductType  int    `json:"product_type"`
 	RequiredInDB bool   `json:"required_in_db"`
 }
+
+// SQLCheckSummary stores the outcome of a configured SQL destination check
+type SQLCheckSummary struct {
+	Checked       int            `json:"checked"`
+	Found         int            `json:"found"`
+	Missing       int            `json:"missing"`
+	Errors        int            `json:"errors"`
+	MissingEvents []MissingEvent `json:"missing_events,omitempty"`
+}
+
+// ConsistencyReport splits the events checked in both MongoDB and MySQL into four cases
+type ConsistencyReport struct {
+	Both      *ConsistencyCase `json:"in_both"`
+	MongoOnly *ConsistencyCase `json:"mongo_only"` // Failures of the MySQL ETL
+	MySQLOnly *ConsistencyCase `json:"mysql_only"` // Failures of the MongoDB ingestion
+	Neither   *ConsistencyCase `json:"neither"`
+}
+
+// ConsistencyCase stores the events of a single consistency case
+type ConsistencyCase struct {
+	Count        int                `json:"count"`
+	ByCollection map[string]int     `json:"by_collection"`
+	ByEventName  map[string]int     `json:"by_event_name"`
+	Events       []ConsistencyEvent `json:"events,omitempty"` // Not listed for in_both
+}
+
+// ConsistencyEvent stores an event of a consistency case
+type ConsistencyEvent struct {
+	ID           string `json:"id"`
+	EntityType   string `json:"entity_type"`
+	EventName    string `json:"event_name"`
+	SessionID    string `json:"session_id"`
+	OffsetID     int    `json:"offset_id"`
+	MySQLOutcome string `json:"mysql_outcome"`
+}
+
+// MySQLSuspiciousEvent stores an event whose closest MySQL row lies outside the time window
+type MySQLSuspiciousEvent struct {
+	ID               string    `json:"id"`
+	EventName        string    `json:"event_name"`
+	SessionID        string    `json:"session_id"`
+	EntityType       string    `json:"entity_type"`
+	EventTime        time.Time `json:"event_time"`
+	MySQLID          int64     `json:"mysql_id"`
+	SessionStartTime time.Time `json:"session_start_time"`
+	SessionEndTime   time.Time `json:"session_end_time"`
+	DateOfCreation   time.Time `json:"date_of_creation"`
+	TimeDelta        string    `json:"time_delta"`
+}
+
+// MySQLUnexpectedEvent stores an event whose MySQL row does not carry an expected screen
+type MySQLUnexpectedEvent struct {
+	ID              string            `json:"id"`
+	EventName       string            `json:"event_name"`
+	SessionID       string            `json:"session_id"`
+	EntityType      string            `json:"entity_type"`
+	ExpectedScreens map[string]string `json:"expected_screens"`
+	ActualScreens   map[string]string `json:"actual_screens"`
+	MySQLID         int64             `json:"mysql_id"`
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"analytics/config"
+	"analytics/db"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// preflightCheck collects the outcome of the preflight checks
+type preflightCheck struct {
+	failures int
+	warnings int
+}
+
+func (p *preflightCheck) pass(format string, args ...interface{}) {
+	fmt.Printf("✅ "+format+"\n", args...)
+}
+
+func (p *preflightCheck) warn(format string, args ...interface{}) {
+	p.warnings++
+	fmt.Printf("⚠️ "+format+"\n", args...)
+}
+
+func (p *preflightCheck) fail(format string, args ...interface{}) {
+	p.failures++
+	fmt.Printf("❌ "+format+"\n", args...)
+}
+
+// runPreflight verifies the environment a validation run needs, without validating any events
+func runPreflight(database *mongo.Database, cfg *config.Configuration) {
+	check := &preflightCheck{}
+	check.pass("Connected to MongoDB database %s", cfg.DatabaseName)
+
+	collections, err := db.ListCollectionNames(database, cfg.QueryTimeout)
+	if err != nil {
+		log.Fatalf("Failed to list collections: %v", err)
+	}
+
+	// The source collection must exist
+	if !collections[cfg.CollectionName] {
+		check.fail("Source collection %s does not exist in %s", cfg.CollectionName, cfg.DatabaseName)
+		finishPreflight(check)
+		return
+	}
+	check.pass("Source collection %s exists", cfg.CollectionName)
+
+	// Sampled documents must decode into EventRecovery
+	sample, decodeErrors, err := db.SampleEventRecoveries(database, cfg.CollectionName, cfg.PreflightSample, cfg.QueryTimeout)
+	if err != nil {
+		log.Fatalf("Failed to sample %s: %v", cfg.CollectionName, err)
+	}
+	for _, decodeErr := range decodeErrors {
+		check.fail("Could not decode %v", decodeErr)
+	}
+	if len(sample) == 0 && len(decodeErrors) == 0 {
+		check.warn("Source collection %s is empty", cfg.CollectionName)
+	} else if len(decodeErrors) == 0 {
+		check.pass("Decoded %d sampled documents", len(sample))
+	}
+
+	// Every entity_type in the sample must have a destination collection
+	sampleIDs := make(map[string]string)
+	sampledEvents, emptyDocs, emptyEntityTypes := 0, 0, 0
+	for _, recovery := range sample {
+		if len(recovery.Events) == 0 {
+			emptyDocs++
+		}
+		for _, event := range recovery.Events {
+			sampledEvents++
+			if event.EntityType == "" {
+				emptyEntityTypes++
+				continue
+			}
+			if _, ok := sampleIDs[event.EntityType]; !ok {
+				sampleIDs[event.EntityType] = event.ID
+			}
+		}
+	}
+	if emptyDocs > 0 {
+		check.warn("%d sampled documents have no events", emptyDocs)
+	}
+	if emptyEntityTypes > 0 {
+		check.warn("%d sampled events have an empty entity_type", emptyEntityTypes)
+	}
+
+	entityTypes := make([]string, 0, len(sampleIDs))
+	for entityType := range sampleIDs {
+		entityTypes = append(entityTypes, entityType)
+	}
+	sort.Strings(entityTypes)
+
+	existingIDs := make(map[string]string)
+	for _, entityType := range entityTypes {
+		if collections[entityType] {
+			check.pass("Destination collection %s exists", entityType)
+			existingIDs[entityType] = sampleIDs[entityType]
+		} else {
+			check.fail("Destination collection %s does not exist", entityType)
+		}
+	}
+
+	// Lookups on existing destinations should use an index
+	for _, indexCheck := range db.CheckDestinationIndexes(database, existingIDs, cfg.QueryTimeout, false) {
+		switch {
+		case indexCheck.Error != nil:
+			check.warn("Could not check indexes on %s: %v", indexCheck.Collection, indexCheck.Error)
+		case indexCheck.CollectionScan || !indexCheck.HasIndex:
+			check.warn("Lookups on %s would not use an index on %s (plan: %v)", indexCheck.Collection, db.MappingIDField, indexCheck.PlanStages)
+		}
+	}
+
+	// MySQL table and columns must exist if MySQL checks are configured
+	if cfg.MySQLDSN != "" {
+		preflightMySQL(check, cfg)
+	}
+
+	// SQL destination checks must connect and prepare their queries
+	for _, sqlCheck := range cfg.SQLChecks {
+		checker, err := db.NewSQLChecker(sqlCheck)
+		if err != nil {
+			check.fail("SQL check %s: %v", sqlCheck.Name, err)
+			continue
+		}
+		checker.Close()
+		check.pass("SQL check %s is ready", sqlCheck.Name)
+	}
+
+	// Estimate the size of a full run
+	docCount, err := db.EstimateDocumentCount(database, cfg.CollectionName, cfg.QueryTimeout)
+	if err != nil {
+		check.warn("Could not estimate document count: %v", err)
+	} else {
+		if cfg.DocLimit > 0 && int64(cfg.DocLimit) < docCount {
+			docCount = int64(cfg.DocLimit)
+		}
+		fmt.Printf("Estimated run size: %d documents", docCount)
+		if len(sample) > 0 {
+			eventsPerDoc := float64(sampledEvents) / float64(len(sample))
+			fmt.Printf(", ~%.0f events (%.1f events per sampled document)", eventsPerDoc*float64(docCount), eventsPerDoc)
+		}
+		fmt.Println()
+	}
+
+	finishPreflight(check)
+}
+
+// preflightMySQL checks the MySQL connection, table and columns
+func preflightMySQL(check *preflightCheck, cfg *config.Configuration) {
+	mysqlDB, err := db.ConnectMySQL(mysqlConfig(cfg))
+	if err != nil {
+		check.fail("MySQL: %v", err)
+		return
+	}
+	defer mysqlDB.Close()
+
+	missing, err := db.CheckMySQLTable(mysqlDB, mysqlConfig(cfg))
+	switch {
+	case err != nil:
+		check.fail("MySQL: %v", err)
+	case len(missing) > 0:
+		check.fail("MySQL table app_tracking_new is missing columns %v", missing)
+	default:
+		check.pass("MySQL table app_tracking_new has all required columns")
+	}
+}
+
+// finishPreflight prints the outcome and exits non-zero if any check failed
+func finishPreflight(check *preflightCheck) {
+	if check.failures > 0 {
+		log.Fatalf("Preflight failed: %d failures, %d warnings", check.failures, check.warnings)
+	}
+	fmt.Printf("Preflight passed with %d warnings\n", check.warnings)
+}
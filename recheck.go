@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"analytics/config"
+	"analytics/models"
+	"analytics/report"
+	"analytics/validator"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// runRecheck validates only the missing and errored events of a saved report, which is
+// much cheaper than a full scan after a pipeline fix or a replay
+func runRecheck(database *mongo.Database, cfg *config.Configuration) int {
+	sourceReport, err := report.LoadReport(cfg.ReportFile)
+	if err != nil {
+		log.Fatalf("Failed to load report: %v", err)
+	}
+
+	eventsByDocument, skipped := report.RecheckEvents(sourceReport)
+	if len(skipped) > 0 {
+		fmt.Printf("⚠️ %d errors in the report name no event that can be re-checked, carrying them over\n", len(skipped))
+	}
+
+	var results []models.Result
+	for documentIndex, events := range eventsByDocument {
+		fmt.Printf("Re-checking %d events from document %d\n", len(events), documentIndex)
+		// Reports do not hold every compared field, so only presence is checked
+		results = append(results, validator.ProcessEventsInDocument(database, events, cfg.QueryTimeout, cfg.MaxConcurrent, documentIndex, nil)...)
+	}
+
+	recheckReport := report.BuildRecheckReport(cfg.ReportFile, results, skipped, report.Options{EventTime: cfg.EventTime})
+	if _, err := report.WriteRecheckReport(recheckReport, cfg.ReportDir); err != nil {
+		log.Fatalf("Failed to write recheck report: %v", err)
+	}
+
+	// Carried-over errors were already counted by the run that found them
+	if cfg.MaxErrors >= 0 && len(recheckReport.Errors)-recheckReport.SkippedCount > cfg.MaxErrors {
+		return exitErrors
+	}
+	if cfg.MaxMissing >= 0 && recheckReport.TotalCount > cfg.MaxMissing {
+		return exitMissing
+	}
+	return exitClean
+}
@@ -0,0 +1,60 @@
+package report
+
+import (
+	"analytics/models"
+)
+
+// buildConsistencyReport classifies every event checked in both stores into one of four cases
+func buildConsistencyReport(combined []models.CombinedResult) *models.ConsistencyReport {
+	consistency := &models.ConsistencyReport{
+		Both:      newConsistencyCase(),
+		MongoOnly: newConsistencyCase(),
+		MySQLOnly: newConsistencyCase(),
+		Neither:   newConsistencyCase(),
+	}
+
+	for _, combinedResult := range combined {
+		var consistencyCase *models.ConsistencyCase
+		switch combinedResult.Consistency() {
+		case models.ConsistencyBoth:
+			consistencyCase = consistency.Both
+		case models.ConsistencyMongoOnly:
+			consistencyCase = consistency.MongoOnly
+		case models.ConsistencyMySQLOnly:
+			consistencyCase = consistency.MySQLOnly
+		case models.ConsistencyNeither:
+			consistencyCase = consistency.Neither
+		default:
+			// Not checked in MySQL or errored in one of the stores
+			continue
+		}
+
+		mongoResult := combinedResult.MongoResult
+		consistencyCase.Count++
+		consistencyCase.ByCollection[mongoResult.CollectionName]++
+		consistencyCase.ByEventName[mongoResult.Event.EventName]++
+
+		// Events present everywhere are only counted
+		if consistencyCase == consistency.Both {
+			continue
+		}
+		consistencyCase.Events = append(consistencyCase.Events, models.ConsistencyEvent{
+			ID:           mongoResult.EventID,
+			EntityType:   mongoResult.EntityType,
+			EventName:    mongoResult.Event.EventName,
+			SessionID:    mongoResult.Event.SessionID,
+			OffsetID:     mongoResult.OffsetID,
+			MySQLOutcome: combinedResult.MySQLResult.Outcome,
+		})
+	}
+
+	return consistency
+}
+
+// newConsistencyCase creates an empty consistency case
+func newConsistencyCase() *models.ConsistencyCase {
+	return &models.ConsistencyCase{
+		ByCollection: make(map[string]int),
+		ByEventName:  make(map[string]int),
+	}
+}
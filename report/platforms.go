@@ -0,0 +1,50 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+
+	"analytics/extract"
+	"analytics/models"
+)
+
+// PlatformBreakdown counts found, missing and errored events per client platform
+func PlatformBreakdown(results []models.Result, extractor extract.PlatformExtractor) map[string]models.PlatformStats {
+	platforms := make(map[string]models.PlatformStats)
+	for _, result := range results {
+		platform := extractor.Platform(result.Event.SessionID)
+		stats := platforms[platform]
+
+		stats.Total++
+		if result.Error != nil {
+			stats.Errors++
+		} else if result.FoundInDest {
+			stats.Found++
+		} else {
+			stats.Missing++
+		}
+
+		platforms[platform] = stats
+	}
+	return platforms
+}
+
+// PrintPlatformBreakdown prints the per-platform counts, platforms with the most missing events first
+func PrintPlatformBreakdown(platforms map[string]models.PlatformStats) {
+	names := make([]string, 0, len(platforms))
+	for name := range platforms {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := platforms[names[i]], platforms[names[j]]
+		if a.Missing != b.Missing {
+			return a.Missing > b.Missing
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		stats := platforms[name]
+		fmt.Printf("    %-10s %d found, %d missing, %d errors\n", name, stats.Found, stats.Missing, stats.Errors)
+	}
+}
@@ -0,0 +1,65 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"analytics/models"
+)
+
+// buildDataQualityReport records which BSON types entity_code was stored as, per collection
+func buildDataQualityReport(results []models.Result) *models.DataQualityReport {
+	quality := &models.DataQualityReport{
+		EntityCodeTypes: make(map[string]int),
+	}
+	byCollection := make(map[string]map[string]int)
+
+	for _, result := range results {
+		codeType := result.Event.EntityCode.OriginalType()
+		quality.EntityCodeTypes[codeType]++
+
+		if byCollection[result.CollectionName] == nil {
+			byCollection[result.CollectionName] = make(map[string]int)
+		}
+		byCollection[result.CollectionName][codeType]++
+	}
+
+	if len(quality.EntityCodeTypes) > 1 {
+		quality.Findings = append(quality.Findings,
+			fmt.Sprintf("entity_code is stored with %d different types: %s",
+				len(quality.EntityCodeTypes), formatTypeCounts(quality.EntityCodeTypes)))
+	}
+
+	collections := make([]string, 0, len(byCollection))
+	for collection := range byCollection {
+		collections = append(collections, collection)
+	}
+	sort.Strings(collections)
+
+	for _, collection := range collections {
+		types := byCollection[collection]
+		if len(types) < 2 {
+			continue
+		}
+
+		if quality.InconsistentEntityCodes == nil {
+			quality.InconsistentEntityCodes = make(map[string]map[string]int)
+		}
+		quality.InconsistentEntityCodes[collection] = types
+		quality.Findings = append(quality.Findings,
+			fmt.Sprintf("collection %s has mixed entity_code types: %s", collection, formatTypeCounts(types)))
+	}
+
+	return quality
+}
+
+// formatTypeCounts formats type counts as "int32=3, string=10"
+func formatTypeCounts(types map[string]int) string {
+	parts := make([]string, 0, len(types))
+	for codeType, count := range types {
+		parts = append(parts, fmt.Sprintf("%s=%d", codeType, count))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,122 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"analytics/models"
+)
+
+// checkErrorPattern matches the MongoDB lookup errors written by BuildMissingDataReport.
+// MySQL errors also match and are filtered out, SQL check errors do not match.
+var checkErrorPattern = regexp.MustCompile(`^Error checking (\S+) in (\S+): `)
+
+// RecheckEvents returns the events of a report worth validating again, grouped by their
+// document index: every missing event plus the events whose MongoDB lookup failed. Errors
+// that name no event and collection to look up, such as empty entity types or MySQL
+// failures, are returned as skipped.
+func RecheckEvents(report models.MissingDataReport) (map[int][]models.Event, []string) {
+	events := make(map[int][]models.Event)
+	seen := make(map[string]bool)
+
+	for collection, missingEvents := range report.ByCollection {
+		for _, missingEvent := range missingEvents {
+			if seen[missingEvent.ID] {
+				continue
+			}
+			seen[missingEvent.ID] = true
+
+			entityType := missingEvent.EntityType
+			if entityType == "" {
+				entityType = collection
+			}
+			events[missingEvent.OffsetID] = append(events[missingEvent.OffsetID], models.Event{
+				ID:         missingEvent.ID,
+				EntityType: entityType,
+				EntityCode: missingEvent.EntityCode,
+				EventName:  missingEvent.EventName,
+				UUID:       missingEvent.UUID,
+				SessionID:  missingEvent.SessionID,
+			})
+		}
+	}
+
+	// Errors only name the event and its collection
+	var skipped []string
+	for _, errMsg := range report.Errors {
+		groups := checkErrorPattern.FindStringSubmatch(errMsg)
+		if groups == nil || groups[2] == "MySQL" {
+			skipped = append(skipped, errMsg)
+			continue
+		}
+		if seen[groups[1]] {
+			continue
+		}
+		seen[groups[1]] = true
+		events[0] = append(events[0], models.Event{ID: groups[1], EntityType: groups[2]})
+	}
+
+	return events, skipped
+}
+
+// BuildRecheckReport sorts the re-validated events of sourceReport into present and still missing.
+// Skipped errors of the source report are carried over unchanged.
+func BuildRecheckReport(sourceReport string, results []models.Result, skipped []string, opts Options) models.RecheckReport {
+	report := models.RecheckReport{
+		Timestamp:    time.Now().Format(time.RFC3339),
+		SourceReport: sourceReport,
+		Checked:      len(results),
+		Present:      make(map[string][]models.MissingEvent),
+		ByCollection: make(map[string][]models.MissingEvent),
+		SkippedCount: len(skipped),
+		Errors:       append([]string(nil), skipped...),
+	}
+
+	for _, result := range results {
+		switch {
+		case result.Error != nil:
+			report.Errors = append(report.Errors, fmt.Sprintf("Error checking %s in %s: %v",
+				result.EventID, result.CollectionName, result.Error))
+		case result.FoundInDest:
+			report.Present[result.CollectionName] = append(report.Present[result.CollectionName], newMissingEvent(result, opts))
+			report.PresentCount++
+		default:
+			report.ByCollection[result.CollectionName] = append(report.ByCollection[result.CollectionName], newMissingEvent(result, opts))
+			report.TotalCount++
+		}
+	}
+
+	return report
+}
+
+// WriteRecheckReport writes the recheck report to a timestamped JSON file in dir and returns its path
+func WriteRecheckReport(report models.RecheckReport, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	timestamp := time.Now().Format("20060102_150405")
+	filename := filepath.Join(dir, fmt.Sprintf("recheck_events_%s.json", timestamp))
+
+	jsonData, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JSON: %v", err)
+	}
+	if err := os.WriteFile(filename, jsonData, 0644); err != nil {
+		return "", fmt.Errorf("failed to write file: %v", err)
+	}
+
+	fmt.Printf("Created recheck report: %s\n", filename)
+	fmt.Printf("  - %d events checked\n", report.Checked)
+	fmt.Printf("  - %d events now present\n", report.PresentCount)
+	fmt.Printf("  - %d events still missing\n", report.TotalCount)
+	if report.SkippedCount > 0 {
+		fmt.Printf("  - %d errors could not be re-checked and were carried over\n", report.SkippedCount)
+	}
+	fmt.Printf("  - %d errors encountered\n", len(report.Errors))
+	return filename, nil
+}
@@ -0,0 +1,82 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+
+	"analytics/models"
+)
+
+func TestRecheckEventsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		errMsg  string
+		event   *models.Event // Event re-checked from the error, nil if skipped
+		skipped bool
+	}{
+		{
+			"mongo lookup error",
+			"Error checking 1700000000000abc in doctalk: context deadline exceeded",
+			&models.Event{ID: "1700000000000abc", EntityType: "doctalk"},
+			false,
+		},
+		{"empty entity type", "Error checking 1700000000000abc in : empty entity_type", nil, true},
+		{"mysql error", "Error checking 1700000000000abc in MySQL: failed to connect to MySQL: dial tcp: connection refused", nil, true},
+		{"sql check error", "Error checking 1700000000000abc in SQL check orders: error querying orders: timeout", nil, true},
+		{"unrelated error", "failed to read destination indexes", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, skipped := RecheckEvents(models.MissingDataReport{Errors: []string{tt.errMsg}})
+
+			if tt.skipped {
+				if !reflect.DeepEqual(skipped, []string{tt.errMsg}) {
+					t.Fatalf("skipped = %q, want the error", skipped)
+				}
+				if len(events) != 0 {
+					t.Fatalf("events = %v, want none", events)
+				}
+				return
+			}
+
+			if len(skipped) != 0 {
+				t.Fatalf("skipped = %q, want none", skipped)
+			}
+			if want := map[int][]models.Event{0: {*tt.event}}; !reflect.DeepEqual(events, want) {
+				t.Fatalf("events = %v, want %v", events, want)
+			}
+		})
+	}
+}
+
+func TestRecheckEventsDeduplicates(t *testing.T) {
+	report := models.MissingDataReport{
+		ByCollection: map[string][]models.MissingEvent{
+			"doctalk": {
+				{ID: "a", EventName: "DETAIL_EXIT", OffsetID: 3},
+				{ID: "a", EventName: "DETAIL_EXIT", OffsetID: 3},
+			},
+			"other": {{ID: "b", EntityType: "other", OffsetID: 5}},
+		},
+		Errors: []string{
+			"Error checking a in doctalk: timeout",
+			"Error checking c in doctalk: timeout",
+			"Error checking c in doctalk: cursor killed",
+		},
+	}
+
+	events, skipped := RecheckEvents(report)
+	if len(skipped) != 0 {
+		t.Fatalf("skipped = %q, want none", skipped)
+	}
+
+	want := map[int][]models.Event{
+		3: {{ID: "a", EntityType: "doctalk", EventName: "DETAIL_EXIT"}},
+		5: {{ID: "b", EntityType: "other"}},
+		0: {{ID: "c", EntityType: "doctalk"}},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+}
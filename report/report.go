@@ -3,21 +3,34 @@ package report
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"time"
 
+	"analytics/extract"
 	"analytics/models"
 )
 
-// CreateMissingDataReport generates a report of missing events
-func CreateMissingDataReport(results []models.Result) {
+// Options controls the optional sections and output of the missing data report
+type Options struct {
+	TopUsers          int    // Number of users in the top-N impact list
+	PseudonymiseUUIDs bool   // Replace user UUIDs with a keyed hash in the output
+	PseudonymSalt     string // Key used when pseudonymising UUIDs
+
+	Platforms extract.PlatformExtractor // Derives the platform breakdown, skipped if nil
+	EventTime extract.TimeExtractor     // Derives event times for the timeline, skipped if nil
+}
+
+// BuildMissingDataReport generates a report of missing events
+// combined holds the MongoDB and MySQL results of each event and is nil if MySQL was not checked.
+func BuildMissingDataReport(results []models.Result, combined []models.CombinedResult, duplicates []models.DuplicateEvent, opts Options) models.MissingDataReport {
 	// Create a report structure
 	report := models.MissingDataReport{
-		Timestamp:    time.Now().Format(time.RFC3339),
-		ByCollection: make(map[string][]models.MissingEvent),
-		Errors:       []string{},
+		Timestamp:       time.Now().Format(time.RFC3339),
+		ByCollection:    make(map[string][]models.MissingEvent),
+		Errors:          []string{},
+		FieldMismatches: make(map[string][]models.MismatchedEvent),
+		CheckedCount:    make(map[string]int),
 	}
 
 	// Count total missing events and gather errors
@@ -26,6 +39,12 @@ func CreateMissingDataReport(results []models.Result) {
 
 	// Group missing events by collection
 	for _, result := range results {
+		// Count checked events so missing ratios can be derived
+		report.CheckedCount[result.CollectionName]++
+
+		// Tally the configured SQL destination checks
+		addSQLCheckResults(&report, result, opts, errorsMap)
+
 		// Handle errors
 		if result.Error != nil {
 			errMsg := fmt.Sprintf("Error checking %s in %s: %v",
@@ -34,21 +53,36 @@ func CreateMissingDataReport(results []models.Result) {
 			continue
 		}
 
+		// Count events that were missing on the first pass but found by a re-check
+		if result.FoundInDest && result.ResolvedAfter > 0 {
+			if report.ResolvedByDelay == nil {
+				report.ResolvedByDelay = make(map[string]int)
+			}
+			report.ResolvedByDelay[result.ResolvedAfter.String()]++
+			report.ResolvedByRecheck++
+		}
+
+		// Record field mismatches for events found in deep-compare mode
+		if result.FoundInDest && len(result.Mismatches) > 0 {
+			mismatchedEvent := models.MismatchedEvent{
+				ID:         result.Event.ID,
+				EventName:  result.Event.EventName,
+				SessionID:  result.Event.SessionID,
+				OffsetID:   result.OffsetID,
+				Mismatches: result.Mismatches,
+			}
+			report.FieldMismatches[result.CollectionName] = append(report.FieldMismatches[result.CollectionName], mismatchedEvent)
+			report.MismatchCount++
+			continue
+		}
+
 		// Skip if found
 		if result.FoundInDest {
 			continue
 		}
 
 		// Create a missing event entry
-		missingEvent := models.MissingEvent{
-			ID:         result.Event.ID,
-			EntityType: result.Event.EntityType,
-			EntityCode: result.Event.EntityCode,
-			EventName:  result.Event.EventName,
-			UUID:       result.Event.UUID,
-			SessionID:  result.Event.SessionID,
-			OffsetID:   result.OffsetID,
-		}
+		missingEvent := newMissingEvent(result, opts)
 
 		// Add to the appropriate collection
 		report.ByCollection[result.CollectionName] = append(report.ByCollection[result.CollectionName], missingEvent)
@@ -57,44 +91,241 @@ func CreateMissingDataReport(results []models.Result) {
 
 	report.TotalCount = totalMissing
 
+	// Gather events missing from MySQL
+	for _, combinedResult := range combined {
+		mysqlResult := combinedResult.MySQLResult
+		if mysqlResult == nil {
+			continue
+		}
+		if mysqlResult.Error != nil {
+			errMsg := fmt.Sprintf("Error checking %s in MySQL: %v", mysqlResult.EventID, mysqlResult.Error)
+			errorsMap[errMsg] = true
+			continue
+		}
+		if mysqlResult.Found {
+			continue
+		}
+		if mysqlResult.Outcome == models.MySQLUnexpectedScreen {
+			unexpected := models.MySQLUnexpectedEvent{
+				ID:              mysqlResult.EventID,
+				EventName:       mysqlResult.EventName,
+				SessionID:       mysqlResult.SessionID,
+				EntityType:      mysqlResult.CollectionName,
+				ExpectedScreens: mysqlResult.ExpectedScreens,
+			}
+			if mysqlResult.MySQLEvent != nil {
+				unexpected.ActualScreens = mysqlResult.MySQLEvent.Screens
+				unexpected.MySQLID = mysqlResult.MySQLEvent.ID
+			}
+			report.MySQLUnexpected = append(report.MySQLUnexpected, unexpected)
+			continue
+		}
+		if mysqlResult.Outcome == models.MySQLSuspicious && mysqlResult.MySQLEvent != nil {
+			report.MySQLSuspicious = append(report.MySQLSuspicious, models.MySQLSuspiciousEvent{
+				ID:               mysqlResult.EventID,
+				EventName:        mysqlResult.EventName,
+				SessionID:        mysqlResult.SessionID,
+				EntityType:       mysqlResult.CollectionName,
+				EventTime:        mysqlResult.EventTime,
+				MySQLID:          mysqlResult.MySQLEvent.ID,
+				SessionStartTime: mysqlResult.MySQLEvent.SessionStartTime,
+				SessionEndTime:   mysqlResult.MySQLEvent.SessionEndTime,
+				DateOfCreation:   mysqlResult.MySQLEvent.DateOfCreation,
+				TimeDelta:        mysqlResult.TimeDelta.String(),
+			})
+			continue
+		}
+
+		report.MySQLMissing = append(report.MySQLMissing, models.MySQLMissingEvent{
+			ID:           mysqlResult.EventID,
+			EventName:    mysqlResult.EventName,
+			SessionID:    mysqlResult.SessionID,
+			EntityType:   mysqlResult.CollectionName,
+			ProductType:  mysqlResult.ProductType,
+			RequiredInDB: true,
+		})
+	}
+	report.MySQLMissingCount = len(report.MySQLMissing)
+	if combined != nil {
+		report.Consistency = buildConsistencyReport(combined)
+	}
+	report.DuplicateCount = len(duplicates)
+	report.Duplicates = duplicates
+	report.Sessions = buildSessionReport(results)
+	report.Users = buildUserReport(results, opts)
+	report.DataQuality = buildDataQualityReport(results)
+	if opts.Platforms != nil {
+		report.Platforms = PlatformBreakdown(results, opts.Platforms)
+	}
+	if opts.EventTime != nil {
+		report.Timeline = buildTimelineReport(results, opts.EventTime)
+	}
+
 	// Convert error map to slice
 	for errMsg := range errorsMap {
 		report.Errors = append(report.Errors, errMsg)
 	}
 
-	// Create report file if there are missing events or errors
-	if totalMissing > 0 || len(report.Errors) > 0 {
-		writeReportToFile(report, totalMissing)
-	} else {
-		fmt.Println("No missing events or errors found, no report file created.")
+	return report
+}
+
+// HasFindings reports whether there are missing events, mismatches, duplicates or errors worth a report file
+func HasFindings(report models.MissingDataReport) bool {
+	return report.TotalCount > 0 || report.MySQLMissingCount > 0 || len(report.MySQLUnexpected) > 0 || len(report.MySQLSuspicious) > 0 ||
+		sqlMissingCount(report) > 0 || report.MismatchCount > 0 || report.DuplicateCount > 0 || len(report.Errors) > 0
+}
+
+// LoadReport reads a report previously written by WriteReportToFile
+func LoadReport(path string) (models.MissingDataReport, error) {
+	var report models.MissingDataReport
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return report, fmt.Errorf("error reading report: %v", err)
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return report, fmt.Errorf("error parsing report: %v", err)
+	}
+	return report, nil
+}
+
+// sqlMissingCount returns the number of events missing across all SQL destination checks
+func sqlMissingCount(report models.MissingDataReport) int {
+	count := 0
+	for _, summary := range report.SQLChecks {
+		count += summary.Missing
+	}
+	return count
+}
+
+// newMissingEvent creates the report entry for a missing event
+func newMissingEvent(result models.Result, opts Options) models.MissingEvent {
+	missingEvent := models.MissingEvent{
+		ID:         result.Event.ID,
+		EntityType: result.Event.EntityType,
+		EntityCode: result.Event.EntityCode,
+		EventName:  result.Event.EventName,
+		UUID:       opts.userID(result.Event.UUID),
+		SessionID:  result.Event.SessionID,
+		OffsetID:   result.OffsetID,
+	}
+	if opts.EventTime != nil {
+		if eventTime, ok := opts.EventTime.EventTime(result.Event); ok {
+			missingEvent.EventTime = eventTime.Format(time.RFC3339Nano)
+		}
+	}
+	return missingEvent
+}
+
+// addSQLCheckResults adds the SQL destination check results of an event to the report
+func addSQLCheckResults(report *models.MissingDataReport, result models.Result, opts Options, errorsMap map[string]bool) {
+	for _, sqlResult := range result.SQLChecks {
+		if report.SQLChecks == nil {
+			report.SQLChecks = make(map[string]*models.SQLCheckSummary)
+		}
+		summary, ok := report.SQLChecks[sqlResult.Check]
+		if !ok {
+			summary = &models.SQLCheckSummary{}
+			report.SQLChecks[sqlResult.Check] = summary
+		}
+
+		summary.Checked++
+		switch {
+		case sqlResult.Error != nil:
+			summary.Errors++
+			errMsg := fmt.Sprintf("Error checking %s in SQL check %s: %v", sqlResult.EventID, sqlResult.Check, sqlResult.Error)
+			errorsMap[errMsg] = true
+		case sqlResult.Found:
+			summary.Found++
+		default:
+			summary.Missing++
+			summary.MissingEvents = append(summary.MissingEvents, newMissingEvent(result, opts))
+		}
 	}
 }
 
-// writeReportToFile writes the report to a JSON file
-func writeReportToFile(report models.MissingDataReport, totalMissing int) {
+// WriteReportToFile writes the report to a JSON file in dir and returns its path. The file is
+// named after the time and the run ID, so runs finishing within the same second do not collide.
+func WriteReportToFile(report models.MissingDataReport, dir string, runID string) (string, error) {
 	// Create directory if it doesn't exist
-	err := os.MkdirAll("missing_data", 0755)
+	err := os.MkdirAll(dir, 0755)
 	if err != nil {
-		log.Fatalf("Failed to create directory: %v", err)
+		return "", fmt.Errorf("failed to create directory: %v", err)
 	}
 
 	// Create a timestamped filename
 	timestamp := time.Now().Format("20060102_150405")
-	filename := filepath.Join("missing_data", fmt.Sprintf("missing_events_%s.json", timestamp))
+	filename := filepath.Join(dir, fmt.Sprintf("missing_events_%s_%s.json", timestamp, runID))
 
 	// Marshal to JSON with indentation for readability
 	jsonData, err := json.MarshalIndent(report, "", "  ")
 	if err != nil {
-		log.Fatalf("Failed to marshal JSON: %v", err)
+		return "", fmt.Errorf("failed to marshal JSON: %v", err)
 	}
 
 	// Write to file
 	err = os.WriteFile(filename, jsonData, 0644)
 	if err != nil {
-		log.Fatalf("Failed to write file: %v", err)
+		return "", fmt.Errorf("failed to write file: %v", err)
 	}
 
 	fmt.Printf("Created missing data report: %s\n", filename)
-	fmt.Printf("  - %d missing events\n", totalMissing)
+	printReportSummary(report)
+	return filename, nil
+}
+
+// printReportSummary prints the headline numbers of each report section
+func printReportSummary(report models.MissingDataReport) {
+	fmt.Printf("  - %d missing events\n", report.TotalCount)
+	if report.ResolvedByRecheck > 0 {
+		fmt.Printf("  - %d events found only by a re-check %v\n", report.ResolvedByRecheck, report.ResolvedByDelay)
+	}
+	if report.MySQLMissingCount > 0 {
+		fmt.Printf("  - %d events missing from MySQL\n", report.MySQLMissingCount)
+	}
+	if len(report.MySQLUnexpected) > 0 {
+		fmt.Printf("  - %d events found in MySQL with unexpected screen\n", len(report.MySQLUnexpected))
+	}
+	if len(report.MySQLSuspicious) > 0 {
+		fmt.Printf("  - %d suspicious MySQL matches outside the time window\n", len(report.MySQLSuspicious))
+	}
+	for name, summary := range report.SQLChecks {
+		fmt.Printf("  - SQL check %s: %d found, %d missing, %d errors\n", name, summary.Found, summary.Missing, summary.Errors)
+	}
+	if report.Consistency != nil {
+		fmt.Printf("  - consistency: %d in both, %d MongoDB only, %d MySQL only, %d in neither\n",
+			report.Consistency.Both.Count, report.Consistency.MongoOnly.Count,
+			report.Consistency.MySQLOnly.Count, report.Consistency.Neither.Count)
+	}
+	if report.MismatchCount > 0 {
+		fmt.Printf("  - %d events with mismatched fields\n", report.MismatchCount)
+	}
+	if report.DuplicateCount > 0 {
+		fmt.Printf("  - %d duplicate event IDs\n", report.DuplicateCount)
+	}
+	if report.Sessions != nil {
+		fmt.Printf("  - %d sessions fully lost, %d partially lost, %d unverified, %d complete\n",
+			report.Sessions.FullyLost, report.Sessions.PartiallyLost, report.Sessions.Unverified, report.Sessions.Complete)
+	}
+	if report.Users != nil && report.Users.AffectedUsers > 0 {
+		fmt.Printf("  - %d users affected, top %d account for %.1f%% of missing events\n",
+			report.Users.AffectedUsers, len(report.Users.TopUsers), report.Users.TopShare)
+		for _, user := range report.Users.TopUsers {
+			fmt.Printf("      %s: %d missing in %d sessions\n", user.UUID, user.MissingCount, user.SessionCount)
+		}
+	}
+	if report.Timeline != nil && report.Timeline.FirstSeen != nil {
+		fmt.Printf("  - missing events span %s to %s\n",
+			report.Timeline.FirstSeen.Format(time.RFC3339), report.Timeline.LastSeen.Format(time.RFC3339))
+	}
+	if report.DataQuality != nil {
+		for _, finding := range report.DataQuality.Findings {
+			fmt.Printf("  - data quality: %s\n", finding)
+		}
+	}
+	if len(report.Platforms) > 0 {
+		fmt.Printf("  - by platform:\n")
+		PrintPlatformBreakdown(report.Platforms)
+	}
 	fmt.Printf("  - %d errors encountered\n", len(report.Errors))
 }
@@ -0,0 +1,75 @@
+package report
+
+import (
+	"sort"
+
+	"analytics/models"
+)
+
+// sessionStatusRank orders session statuses from worst to best
+var sessionStatusRank = map[string]int{
+	models.SessionFullyLost:     0,
+	models.SessionPartiallyLost: 1,
+	models.SessionUnverified:    2,
+	models.SessionComplete:      3,
+}
+
+// buildSessionReport groups every validated event by session and classifies each session
+func buildSessionReport(results []models.Result) *models.SessionReport {
+	sessions := make(map[string]*models.SessionSummary)
+
+	for _, result := range results {
+		sessionID := result.Event.SessionID
+		summary, ok := sessions[sessionID]
+		if !ok {
+			summary = &models.SessionSummary{SessionID: sessionID}
+			sessions[sessionID] = summary
+		}
+
+		summary.Recovered++
+		if result.Error != nil {
+			summary.Errors++
+		} else if result.FoundInDest {
+			summary.Present++
+		} else {
+			summary.Missing++
+		}
+	}
+
+	sessionReport := &models.SessionReport{}
+	for _, summary := range sessions {
+		switch {
+		case summary.Present == summary.Recovered:
+			summary.Status = models.SessionComplete
+			sessionReport.Complete++
+		case summary.Missing == 0:
+			// Lookups failed, so the session can't be called complete or lost
+			summary.Status = models.SessionUnverified
+			sessionReport.Unverified++
+		case summary.Present == 0:
+			summary.Status = models.SessionFullyLost
+			sessionReport.FullyLost++
+		default:
+			summary.Status = models.SessionPartiallyLost
+			sessionReport.PartiallyLost++
+		}
+		sessionReport.Sessions = append(sessionReport.Sessions, *summary)
+	}
+
+	// Worst sessions first: by status, then by missing count and share of lost events
+	sort.Slice(sessionReport.Sessions, func(i, j int) bool {
+		a, b := sessionReport.Sessions[i], sessionReport.Sessions[j]
+		if sessionStatusRank[a.Status] != sessionStatusRank[b.Status] {
+			return sessionStatusRank[a.Status] < sessionStatusRank[b.Status]
+		}
+		if a.Missing != b.Missing {
+			return a.Missing > b.Missing
+		}
+		if a.Missing*b.Recovered != b.Missing*a.Recovered {
+			return a.Missing*b.Recovered > b.Missing*a.Recovered
+		}
+		return a.SessionID < b.SessionID
+	})
+
+	return sessionReport
+}
@@ -0,0 +1,123 @@
+package report
+
+import (
+	"time"
+
+	"analytics/models"
+)
+
+// Summarise builds the run summary from the validation results and the report
+func Summarise(runID string, startedAt time.Time, documents int, results []models.Result, report models.MissingDataReport) models.RunSummary {
+	summary := models.RunSummary{
+		RunID:             runID,
+		StartedAt:         startedAt,
+		FinishedAt:        time.Now(),
+		Documents:         documents,
+		Events:            len(results),
+		Duplicates:        report.DuplicateCount,
+		Mismatches:        report.MismatchCount,
+		MySQLMissing:      report.MySQLMissingCount,
+		Errors:            len(report.Errors),
+		ResolvedByRecheck: report.ResolvedByRecheck,
+	}
+
+	for _, result := range results {
+		if result.Error != nil {
+			continue
+		}
+		if result.FoundInDest {
+			summary.Found++
+		} else {
+			summary.Missing++
+		}
+	}
+
+	return summary
+}
+
+// ResultFindings returns a finding for each result that was not found or could not be checked
+func ResultFindings(runID string, results []models.Result) []models.Finding {
+	var findings []models.Finding
+	now := time.Now()
+
+	for _, result := range results {
+		finding := models.Finding{
+			RunID:      runID,
+			Collection: result.CollectionName,
+			EventID:    result.EventID,
+			CreatedAt:  now,
+		}
+		switch {
+		case result.Error != nil:
+			finding.Type = models.FindingError
+			finding.Message = result.Error.Error()
+		case !result.FoundInDest:
+			finding.Type = models.FindingMissing
+			finding.Detail = models.MissingEvent{
+				ID:         result.Event.ID,
+				EntityType: result.Event.EntityType,
+				EntityCode: result.Event.EntityCode,
+				EventName:  result.Event.EventName,
+				SessionID:  result.Event.SessionID,
+				OffsetID:   result.OffsetID,
+			}
+		default:
+			continue
+		}
+		findings = append(findings, finding)
+	}
+
+	return findings
+}
+
+// Findings flattens the report into one finding per missing event, duplicate and error
+func Findings(runID string, report models.MissingDataReport) []models.Finding {
+	var findings []models.Finding
+	now := time.Now()
+
+	for collection, missingEvents := range report.ByCollection {
+		for _, missingEvent := range missingEvents {
+			findings = append(findings, models.Finding{
+				RunID:      runID,
+				Type:       models.FindingMissing,
+				Collection: collection,
+				EventID:    missingEvent.ID,
+				Detail:     missingEvent,
+				CreatedAt:  now,
+			})
+		}
+	}
+
+	for _, mysqlMissing := range report.MySQLMissing {
+		findings = append(findings, models.Finding{
+			RunID:      runID,
+			Type:       models.FindingMySQLMissing,
+			Collection: mysqlMissing.EntityType,
+			EventID:    mysqlMissing.ID,
+			Detail:     mysqlMissing,
+			CreatedAt:  now,
+		})
+	}
+
+	for _, duplicate := range report.Duplicates {
+		findings = append(findings, models.Finding{
+			RunID:      runID,
+			Type:       models.FindingDuplicate,
+			Collection: duplicate.EntityType,
+			EventID:    duplicate.ID,
+			Detail:     duplicate,
+			CreatedAt:  now,
+		})
+	}
+
+	for _, errMsg := range report.Errors {
+		findings = append(findings, models.Finding{
+			RunID:     runID,
+			Type:      models.FindingError,
+			Message:   errMsg,
+			CreatedAt: now,
+		})
+	}
+
+	return findings
+}
@@ -0,0 +1,73 @@
+package report
+
+import (
+	"time"
+
+	"analytics/extract"
+	"analytics/models"
+)
+
+// Bucket formats for the missing event histograms, always in UTC
+const (
+	hourBucketFormat = "2006-01-02T15:00Z"
+	dayBucketFormat  = "2006-01-02"
+)
+
+// buildTimelineReport builds hourly and daily histograms of missing events per collection
+func buildTimelineReport(results []models.Result, extractor extract.TimeExtractor) *models.TimelineReport {
+	timeline := &models.TimelineReport{
+		ByCollection: make(map[string]*models.CollectionTimeline),
+	}
+
+	var first, last time.Time
+	for _, result := range results {
+		if result.Error != nil || result.FoundInDest {
+			continue
+		}
+
+		eventTime, ok := extractor.EventTime(result.Event)
+		if !ok {
+			timeline.Undated++
+			continue
+		}
+
+		collection, ok := timeline.ByCollection[result.CollectionName]
+		if !ok {
+			collection = &models.CollectionTimeline{
+				Hourly: make(map[string]int),
+				Daily:  make(map[string]int),
+			}
+			timeline.ByCollection[result.CollectionName] = collection
+		}
+
+		collection.Hourly[eventTime.Format(hourBucketFormat)]++
+		collection.Daily[eventTime.Format(dayBucketFormat)]++
+		collection.FirstSeen = earliest(collection.FirstSeen, eventTime)
+		collection.LastSeen = latest(collection.LastSeen, eventTime)
+
+		first = earliest(first, eventTime)
+		last = latest(last, eventTime)
+	}
+
+	if !first.IsZero() {
+		timeline.FirstSeen = &first
+		timeline.LastSeen = &last
+	}
+	return timeline
+}
+
+// earliest returns the earlier of two times, treating the zero time as unset
+func earliest(current, candidate time.Time) time.Time {
+	if current.IsZero() || candidate.Before(current) {
+		return candidate
+	}
+	return current
+}
+
+// latest returns the later of two times
+func latest(current, candidate time.Time) time.Time {
+	if candidate.After(current) {
+		return candidate
+	}
+	return current
+}
@@ -0,0 +1,110 @@
+package report
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"analytics/models"
+)
+
+// buildUserReport aggregates missing events by the UUID of the user that sent them
+func buildUserReport(results []models.Result, opts Options) *models.UserReport {
+	type userAggregate struct {
+		missing     int
+		sessions    map[string]bool
+		collections map[string]bool
+		eventNames  map[string]bool
+	}
+
+	users := make(map[string]*userAggregate)
+	totalMissing := 0
+
+	for _, result := range results {
+		if result.Error != nil || result.FoundInDest {
+			continue
+		}
+
+		aggregate, ok := users[result.Event.UUID]
+		if !ok {
+			aggregate = &userAggregate{
+				sessions:    make(map[string]bool),
+				collections: make(map[string]bool),
+				eventNames:  make(map[string]bool),
+			}
+			users[result.Event.UUID] = aggregate
+		}
+
+		aggregate.missing++
+		aggregate.sessions[result.Event.SessionID] = true
+		aggregate.collections[result.CollectionName] = true
+		aggregate.eventNames[result.Event.EventName] = true
+		totalMissing++
+	}
+
+	userReport := &models.UserReport{
+		AffectedUsers: len(users),
+		TopN:          opts.TopUsers,
+		Pseudonymised: opts.PseudonymiseUUIDs,
+	}
+
+	for uuid, aggregate := range users {
+		userReport.Users = append(userReport.Users, models.UserImpact{
+			UUID:         opts.userID(uuid),
+			MissingCount: aggregate.missing,
+			SessionCount: len(aggregate.sessions),
+			Sessions:     sortedKeys(aggregate.sessions),
+			Collections:  sortedKeys(aggregate.collections),
+			EventNames:   sortedKeys(aggregate.eventNames),
+		})
+	}
+
+	// Most affected users first
+	sort.Slice(userReport.Users, func(i, j int) bool {
+		a, b := userReport.Users[i], userReport.Users[j]
+		if a.MissingCount != b.MissingCount {
+			return a.MissingCount > b.MissingCount
+		}
+		return a.UUID < b.UUID
+	})
+
+	// Build the top-N list and the share of missing events it accounts for
+	topN := opts.TopUsers
+	if topN > len(userReport.Users) {
+		topN = len(userReport.Users)
+	}
+	if topN > 0 {
+		userReport.TopUsers = userReport.Users[:topN]
+		topMissing := 0
+		for _, user := range userReport.TopUsers {
+			topMissing += user.MissingCount
+		}
+		if totalMissing > 0 {
+			userReport.TopShare = float64(topMissing) * 100 / float64(totalMissing)
+		}
+	}
+
+	return userReport
+}
+
+// userID returns the UUID as it should appear in the report, pseudonymised if requested
+func (opts Options) userID(uuid string) string {
+	if !opts.PseudonymiseUUIDs || uuid == "" {
+		return uuid
+	}
+
+	mac := hmac.New(sha256.New, []byte(opts.PseudonymSalt))
+	mac.Write([]byte(uuid))
+	return "user-" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// sortedKeys returns the keys of a set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
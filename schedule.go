@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"analytics/config"
+	"analytics/models"
+
+	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// scheduleEntry records one tick of the schedule command
+type scheduleEntry struct {
+	Tick    time.Time          `json:"tick"`
+	Skipped bool               `json:"skipped,omitempty"` // The previous run was still going
+	Summary *models.RunSummary `json:"summary,omitempty"`
+	Error   string             `json:"error,omitempty"`
+}
+
+// runHistory keeps the most recent schedule entries
+type runHistory struct {
+	mu      sync.Mutex
+	limit   int
+	entries []scheduleEntry
+}
+
+// add records an entry, dropping the oldest once the limit is reached
+func (h *runHistory) add(entry scheduleEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > h.limit {
+		h.entries = append([]scheduleEntry(nil), h.entries[len(h.entries)-h.limit:]...)
+	}
+}
+
+// ServeHTTP returns the history as JSON, newest entry last
+func (h *runHistory) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	entries := append([]scheduleEntry(nil), h.entries...)
+	h.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// runSchedule validates repeatedly on a cron schedule, reusing the MongoDB connection pool between runs
+func runSchedule(database *mongo.Database, cfg *config.Configuration) {
+	schedule, err := cron.ParseStandard(cfg.Cron)
+	if err != nil {
+		log.Fatalf("Invalid -cron: %v", err)
+	}
+
+	history := &runHistory{limit: cfg.ScheduleHistory}
+	if cfg.StatusAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/runs", history)
+		go func() {
+			if err := http.ListenAndServe(cfg.StatusAddr, mux); err != nil {
+				fmt.Printf("❌ Status API stopped: %v\n", err)
+			}
+		}()
+		fmt.Printf("Serving schedule history on %s/runs\n", cfg.StatusAddr)
+	}
+
+	// A tick is skipped rather than queued while the previous run is still going
+	var running atomic.Bool
+	scheduler := cron.New()
+	scheduler.Schedule(schedule, cron.FuncJob(func() {
+		tick := time.Now()
+		if !running.CompareAndSwap(false, true) {
+			fmt.Printf("⚠️ Skipping run at %s, the previous run is still going\n", tick.Format(time.RFC3339))
+			history.add(scheduleEntry{Tick: tick, Skipped: true})
+			return
+		}
+		defer running.Store(false)
+
+		fmt.Printf("Starting scheduled run at %s\n", tick.Format(time.RFC3339))
+		summary, err := validate(database, cfg, tick)
+		if err != nil {
+			fmt.Printf("❌ Scheduled run failed: %v\n", err)
+			history.add(scheduleEntry{Tick: tick, Error: err.Error()})
+		} else {
+			history.add(scheduleEntry{Tick: tick, Summary: &summary})
+		}
+		fmt.Printf("Next run at %s\n", schedule.Next(time.Now()).Format(time.RFC3339))
+	}))
+
+	scheduler.Start()
+	fmt.Printf("Scheduled validation %q, first run at %s\n", cfg.Cron, schedule.Next(time.Now()).Format(time.RFC3339))
+
+	// Run until interrupted, then let a run in progress finish
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	fmt.Println("Stopping scheduler, waiting for the current run to finish")
+	<-scheduler.Stop().Done()
+}
@@ -0,0 +1,38 @@
+package sink
+
+import (
+	"fmt"
+
+	"analytics/models"
+	"analytics/report"
+)
+
+// FileSink writes the report to a JSON file named after the run when there is something to report
+type FileSink struct {
+	Dir string
+}
+
+// Name implements ResultSink
+func (s *FileSink) Name() string {
+	return "file"
+}
+
+// Write does nothing, the file is written once the report is complete
+func (s *FileSink) Write(runID string, results []models.Result) error {
+	return nil
+}
+
+// Finish implements ResultSink
+func (s *FileSink) Finish(summary *models.RunSummary, missingReport models.MissingDataReport) error {
+	if !report.HasFindings(missingReport) {
+		fmt.Println("No missing events, mismatches, duplicates or errors found, no report file created.")
+		return nil
+	}
+
+	path, err := report.WriteReportToFile(missingReport, s.Dir, summary.RunID)
+	if err != nil {
+		return err
+	}
+	summary.ReportPath = path
+	return nil
+}
@@ -0,0 +1,42 @@
+package sink
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"analytics/db"
+	"analytics/models"
+	"analytics/report"
+)
+
+// MongoSink stores the run summary, a config snapshot and the findings in MongoDB
+type MongoSink struct {
+	DB                 *mongo.Database
+	RunsCollection     string
+	FindingsCollection string
+	Config             map[string]string
+	TimeoutSec         int
+}
+
+// Name implements ResultSink
+func (s *MongoSink) Name() string {
+	return "mongo"
+}
+
+// Write does nothing, findings are stored from the complete report so duplicates and MySQL results are included
+func (s *MongoSink) Write(runID string, results []models.Result) error {
+	return nil
+}
+
+// Finish implements ResultSink
+func (s *MongoSink) Finish(summary *models.RunSummary, missingReport models.MissingDataReport) error {
+	run := models.ValidationRun{RunID: summary.RunID, Summary: *summary, Config: s.Config}
+	findings := report.Findings(summary.RunID, missingReport)
+	if err := db.PersistRun(s.DB, s.RunsCollection, s.FindingsCollection, run, findings, s.TimeoutSec); err != nil {
+		return err
+	}
+
+	fmt.Printf("Stored run %s with %d findings in %s\n", summary.RunID, len(findings), s.FindingsCollection)
+	return nil
+}
@@ -0,0 +1,58 @@
+package sink
+
+import (
+	"fmt"
+
+	"analytics/models"
+)
+
+// ResultSink receives validation results as each document is processed, and the final report once the run ends
+type ResultSink interface {
+	// Name identifies the sink in log messages
+	Name() string
+	// Write receives the results of one processed document
+	Write(runID string, results []models.Result) error
+	// Finish receives the run summary and the complete report. Sinks that produce a
+	// report file record its path on the summary for the sinks that follow.
+	Finish(summary *models.RunSummary, report models.MissingDataReport) error
+}
+
+// Fanout forwards results to several sinks. A failing sink is logged and
+// never stops the others from receiving the same results.
+type Fanout struct {
+	sinks  []ResultSink
+	failed map[string]int
+}
+
+// NewFanout creates a fanout over sinks, which are called in the order given
+func NewFanout(sinks ...ResultSink) *Fanout {
+	return &Fanout{
+		sinks:  sinks,
+		failed: make(map[string]int),
+	}
+}
+
+// Write forwards the results of one document to every sink
+func (f *Fanout) Write(runID string, results []models.Result) {
+	for _, sink := range f.sinks {
+		if err := sink.Write(runID, results); err != nil {
+			f.failed[sink.Name()]++
+			fmt.Printf("⚠️ Sink %s failed to write results: %v\n", sink.Name(), err)
+		}
+	}
+}
+
+// Finish hands the summary and report to every sink and returns the number of sinks that failed at any point
+func (f *Fanout) Finish(summary *models.RunSummary, report models.MissingDataReport) int {
+	for _, sink := range f.sinks {
+		if err := sink.Finish(summary, report); err != nil {
+			f.failed[sink.Name()]++
+			fmt.Printf("❌ Sink %s failed to finish: %v\n", sink.Name(), err)
+		}
+	}
+
+	for name, count := range f.failed {
+		fmt.Printf("⚠️ Sink %s had %d failures this run\n", name, count)
+	}
+	return len(f.failed)
+}
@@ -0,0 +1,41 @@
+package sink
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"analytics/models"
+	"analytics/report"
+)
+
+// StdoutSink prints each missing or errored event and the final summary as JSON lines
+type StdoutSink struct {
+	out io.Writer
+}
+
+// NewStdoutSink creates a sink printing to standard output
+func NewStdoutSink() *StdoutSink {
+	return &StdoutSink{out: os.Stdout}
+}
+
+// Name implements ResultSink
+func (s *StdoutSink) Name() string {
+	return "stdout"
+}
+
+// Write implements ResultSink
+func (s *StdoutSink) Write(runID string, results []models.Result) error {
+	encoder := json.NewEncoder(s.out)
+	for _, finding := range report.ResultFindings(runID, results) {
+		if err := encoder.Encode(finding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Finish implements ResultSink
+func (s *StdoutSink) Finish(summary *models.RunSummary, missingReport models.MissingDataReport) error {
+	return json.NewEncoder(s.out).Encode(summary)
+}
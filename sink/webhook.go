@@ -0,0 +1,138 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"analytics/models"
+	"analytics/report"
+)
+
+// webhookQueueSize is the number of pending finding batches before new ones are dropped
+const webhookQueueSize = 100
+
+// WebhookSink POSTs findings as they arrive and the final summary to an HTTP endpoint. Findings
+// are delivered in the background so a slow endpoint never holds up validation, and the sink
+// stops sending after the first failed delivery.
+type WebhookSink struct {
+	URL    string
+	client *http.Client
+	queue  chan webhookPayload
+	done   chan struct{}
+
+	mu      sync.Mutex
+	err     error // First failed delivery
+	dropped int   // Findings not delivered
+}
+
+// webhookPayload is the JSON body of every webhook request
+type webhookPayload struct {
+	Type     string             `json:"type"` // "findings" or "summary"
+	RunID    string             `json:"run_id"`
+	Findings []models.Finding   `json:"findings,omitempty"`
+	Summary  *models.RunSummary `json:"summary,omitempty"`
+}
+
+// NewWebhookSink creates a sink posting to url and starts its background sender
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	s := &WebhookSink{
+		URL:    url,
+		client: &http.Client{Timeout: timeout},
+		queue:  make(chan webhookPayload, webhookQueueSize),
+		done:   make(chan struct{}),
+	}
+	go s.send()
+	return s
+}
+
+// Name implements ResultSink
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+// Write implements ResultSink, queueing the findings without waiting for delivery
+func (s *WebhookSink) Write(runID string, results []models.Result) error {
+	findings := report.ResultFindings(runID, results)
+	if len(findings) == 0 {
+		return nil
+	}
+
+	select {
+	case s.queue <- webhookPayload{Type: "findings", RunID: runID, Findings: findings}:
+	default:
+		s.drop(len(findings))
+	}
+	return nil
+}
+
+// Finish implements ResultSink, waiting for queued findings before posting the summary
+func (s *WebhookSink) Finish(summary *models.RunSummary, missingReport models.MissingDataReport) error {
+	close(s.queue)
+	<-s.done
+
+	s.mu.Lock()
+	err, dropped := s.err, s.dropped
+	s.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("%v (%d findings not delivered, summary not sent)", err, dropped)
+	}
+	if dropped > 0 {
+		fmt.Printf("⚠️ Webhook queue was full, %d findings not delivered\n", dropped)
+	}
+	return s.post(webhookPayload{Type: "summary", RunID: summary.RunID, Summary: summary})
+}
+
+// send delivers queued findings until the queue is closed
+func (s *WebhookSink) send() {
+	defer close(s.done)
+	for payload := range s.queue {
+		s.mu.Lock()
+		failed := s.err != nil
+		s.mu.Unlock()
+		if failed {
+			s.drop(len(payload.Findings))
+			continue
+		}
+
+		if err := s.post(payload); err != nil {
+			fmt.Printf("⚠️ Sink webhook failed, no more findings will be sent this run: %v\n", err)
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+			s.drop(len(payload.Findings))
+		}
+	}
+}
+
+// drop counts findings that were not delivered
+func (s *WebhookSink) drop(count int) {
+	s.mu.Lock()
+	s.dropped += count
+	s.mu.Unlock()
+}
+
+// post sends payload as JSON and fails on any non-2xx response
+func (s *WebhookSink) post(payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	resp, err := s.client.Post(s.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package validator
+
+import (
+	"reflect"
+	"sort"
+
+	"analytics/models"
+)
+
+// EventIndex tracks every position an event ID appears at across recovery documents
+type EventIndex struct {
+	occurrences map[string][]models.EventOccurrence
+	first       map[string]models.Event
+	identical   map[string]bool
+}
+
+// NewEventIndex creates an empty event ID index
+func NewEventIndex() *EventIndex {
+	return &EventIndex{
+		occurrences: make(map[string][]models.EventOccurrence),
+		first:       make(map[string]models.Event),
+		identical:   make(map[string]bool),
+	}
+}
+
+// Add registers the events of a recovery document and returns only the events
+// whose ID has not been seen before, so each unique ID is validated once
+func (idx *EventIndex) Add(recovery models.EventRecovery, documentIndex int) []models.Event {
+	var unique []models.Event
+
+	for position, event := range recovery.Events {
+		// Events without an ID cannot be deduplicated, validate them as they are
+		if event.ID == "" {
+			unique = append(unique, event)
+			continue
+		}
+
+		occurrence := models.EventOccurrence{
+			DocumentID: recovery.ID.Hex(),
+			OffsetID:   documentIndex,
+			Position:   position,
+		}
+
+		firstEvent, seen := idx.first[event.ID]
+		if !seen {
+			idx.first[event.ID] = event
+			idx.identical[event.ID] = true
+			unique = append(unique, event)
+		} else if !reflect.DeepEqual(firstEvent, event) {
+			idx.identical[event.ID] = false
+		}
+
+		idx.occurrences[event.ID] = append(idx.occurrences[event.ID], occurrence)
+	}
+
+	return unique
+}
+
+// Duplicates returns every event ID that appeared more than once, sorted by ID
+func (idx *EventIndex) Duplicates() []models.DuplicateEvent {
+	var duplicates []models.DuplicateEvent
+	for id, occurrences := range idx.occurrences {
+		if len(occurrences) < 2 {
+			continue
+		}
+
+		event := idx.first[id]
+		duplicates = append(duplicates, models.DuplicateEvent{
+			ID:          id,
+			EntityType:  event.EntityType,
+			EventName:   event.EventName,
+			Count:       len(occurrences),
+			Identical:   idx.identical[id],
+			Occurrences: occurrences,
+		})
+	}
+
+	sort.Slice(duplicates, func(i, j int) bool {
+		return duplicates[i].ID < duplicates[j].ID
+	})
+	return duplicates
+}
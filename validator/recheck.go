@@ -0,0 +1,80 @@
+package validator
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"analytics/models"
+)
+
+// RecheckMissing looks up events that were not found again after each delay, measured from
+// the end of the first pass, so events that only reach their destination late are not
+// reported as missing. Results are updated in place and the final results of all re-checked
+// events are returned.
+func RecheckMissing(db *mongo.Database, results []models.Result, delays []time.Duration, timeoutSec int, maxConcurrent int, compareFields []models.FieldPair) []models.Result {
+	firstPassDone := time.Now()
+
+	// Pending results by document index, so re-checked results keep their offset
+	pending := make(map[int][]int)
+	for i, result := range results {
+		if result.Error == nil && !result.FoundInDest {
+			pending[result.OffsetID] = append(pending[result.OffsetID], i)
+		}
+	}
+	rechecked := make([]int, 0, len(pending))
+	for _, indexes := range pending {
+		rechecked = append(rechecked, indexes...)
+	}
+
+	for _, delay := range delays {
+		if len(pending) == 0 {
+			break
+		}
+
+		count := 0
+		for _, indexes := range pending {
+			count += len(indexes)
+		}
+		wait := time.Until(firstPassDone.Add(delay))
+		fmt.Printf("Re-checking %d missing events in %s (%s after the first pass)\n", count, wait.Round(time.Second), delay)
+		time.Sleep(wait)
+
+		resolved := 0
+		for documentIndex, indexes := range pending {
+			events := make([]models.Event, 0, len(indexes))
+			byID := make(map[string]int, len(indexes))
+			for _, i := range indexes {
+				events = append(events, results[i].Event)
+				byID[results[i].EventID] = i
+			}
+
+			var stillMissing []int
+			for _, result := range ProcessEventsInDocument(db, events, timeoutSec, maxConcurrent, documentIndex, compareFields) {
+				i := byID[result.EventID]
+				// A failed re-check leaves the event missing for the next delay
+				if result.Error == nil && result.FoundInDest {
+					result.ResolvedAfter = delay
+					results[i] = result
+					resolved++
+					continue
+				}
+				stillMissing = append(stillMissing, i)
+			}
+
+			if len(stillMissing) > 0 {
+				pending[documentIndex] = stillMissing
+			} else {
+				delete(pending, documentIndex)
+			}
+		}
+		fmt.Printf("Re-check after %s found %d of %d events\n", delay, resolved, count)
+	}
+
+	final := make([]models.Result, 0, len(rechecked))
+	for _, i := range rechecked {
+		final = append(final, results[i])
+	}
+	return final
+}
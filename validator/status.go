@@ -0,0 +1,44 @@
+package validator
+
+import (
+	"time"
+
+	"analytics/models"
+)
+
+// BuildValidationStatus summarises the outcome of every event in a recovery document.
+// resultsByID holds the result of each unique event ID validated so far in the run.
+func BuildValidationStatus(recovery models.EventRecovery, resultsByID map[string]models.Result, runID string) models.ValidationStatus {
+	status := models.ValidationStatus{
+		RunID:     runID,
+		CheckedAt: time.Now().UTC(),
+		Events:    make([]models.EventStatus, 0, len(recovery.Events)),
+	}
+
+	for _, event := range recovery.Events {
+		eventStatus := models.EventStatus{ID: event.ID, Status: models.EventStatusError}
+
+		if result, ok := resultsByID[event.ID]; ok && event.ID != "" {
+			switch {
+			case result.Error != nil:
+				eventStatus.Status = models.EventStatusError
+			case result.FoundInDest:
+				eventStatus.Status = models.EventStatusFound
+			default:
+				eventStatus.Status = models.EventStatusMissing
+			}
+		}
+
+		switch eventStatus.Status {
+		case models.EventStatusFound:
+			status.Found++
+		case models.EventStatusMissing:
+			status.Missing++
+		default:
+			status.Errors++
+		}
+		status.Events = append(status.Events, eventStatus)
+	}
+
+	return status
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,7 +16,8 @@ import (
 )
 
 // ProcessEventsInDocument processes all events in a document with concurrency control
-func ProcessEventsInDocument(db *mongo.Database, events []models.Event, timeoutSec int, maxConcurrent int, documentIndex int) []models.Result {
+// When compareFields is non-empty, found events are also compared field by field against their destination document.
+func ProcessEventsInDocument(db *mongo.Database, events []models.Event, timeoutSec int, maxConcurrent int, documentIndex int, compareFields []models.FieldPair) []models.Result {
 	var results []models.Result
 	var resultsMutex sync.Mutex // To safely append to results from multiple goroutines
 
@@ -39,7 +41,7 @@ func ProcessEventsInDocument(db *mongo.Database, events []models.Event, timeoutS
 			for attempt := 1; attempt <= 2; attempt++ {
 				// Use longer timeout for retries
 				attemptTimeout := timeoutSec * attempt
-				result = validateEvent(db, evt, attemptTimeout)
+				result = validateEvent(db, evt, attemptTimeout, compareFields)
 
 				//set the document index in the result
 				result.OffsetID = documentIndex
@@ -63,6 +65,9 @@ func ProcessEventsInDocument(db *mongo.Database, events []models.Event, timeoutS
 			if result.Error != nil {
 				fmt.Printf("Error checking event %s in collection %s: %v\n",
 					result.EventID, result.CollectionName, result.Error)
+			} else if result.FoundInDest && len(result.Mismatches) > 0 {
+				fmt.Printf("⚠️ Event %s found in %s collection with %d mismatched fields\n",
+					result.EventID, result.CollectionName, len(result.Mismatches))
 			} else if result.FoundInDest {
 				fmt.Printf("✅ Event %s found in %s collection\n", result.EventID, result.CollectionName)
 			} else {
@@ -77,23 +82,29 @@ func ProcessEventsInDocument(db *mongo.Database, events []models.Event, timeoutS
 	wg.Wait()
 
 	// Count results
-	var found, notFound, errored int
+	var found, notFound, errored, mismatched int
 	for _, result := range results {
 		if result.Error != nil {
 			errored++
 		} else if result.FoundInDest {
 			found++
+			if len(result.Mismatches) > 0 {
+				mismatched++
+			}
 		} else {
 			notFound++
 		}
 	}
 
 	fmt.Printf("Summary: %d events found, %d events not found, %d errors\n", found, notFound, errored)
+	if len(compareFields) > 0 {
+		fmt.Printf("Deep compare: %d found events with mismatched fields\n", mismatched)
+	}
 	return results
 }
 
 // validateEvent checks if an event exists in its destination collection
-func validateEvent(db *mongo.Database, event models.Event, timeoutSec int) models.Result {
+func validateEvent(db *mongo.Database, event models.Event, timeoutSec int, compareFields []models.FieldPair) models.Result {
 	// Initialize result
 	result := models.Result{
 		EventID:        event.ID,
@@ -119,6 +130,10 @@ func validateEvent(db *mongo.Database, event models.Event, timeoutSec int) model
 	// Query the collection for the event ID
 	filter := bson.M{"event.mappingId": event.ID}
 
+	if len(compareFields) > 0 {
+		return compareEvent(ctx, collection, filter, result, compareFields, timeoutSec)
+	}
+
 	// Use CountDocuments with timeout options
 	countOptions := options.Count().
 		SetMaxTime(time.Duration(timeoutSec) * time.Second)
@@ -134,6 +149,86 @@ func validateEvent(db *mongo.Database, event models.Event, timeoutSec int) model
 	return result
 }
 
+// compareEvent fetches the matched destination document and compares the configured field pairs
+func compareEvent(ctx context.Context, collection *mongo.Collection, filter bson.M, result models.Result, compareFields []models.FieldPair, timeoutSec int) models.Result {
+	// Only fetch the fields we are going to compare, plus the mapping ID that picks
+	// the matched element when event is an array
+	projection := bson.M{}
+	for _, pair := range compareFields {
+		projection[pair.Dest] = 1
+	}
+	if _, whole := projection["event"]; !whole {
+		projection["event.mappingId"] = 1
+	}
+
+	findOptions := options.FindOne().
+		SetProjection(projection).
+		SetMaxTime(time.Duration(timeoutSec) * time.Second)
+
+	var destDoc bson.M
+	err := collection.FindOne(ctx, filter, findOptions).Decode(&destDoc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return result
+		}
+		result.Error = err
+		return result
+	}
+
+	result.FoundInDest = true
+	for _, pair := range compareFields {
+		sourceValue, _ := result.Event.FieldValue(pair.Source)
+		destValue, ok := lookupPath(destDoc, pair.Dest, result.EventID)
+		destCompared := fmt.Sprint(destValue)
+		if pair.Source == "entity_code" {
+			// The source side is already canonical, normalise the destination the same way
+			destCompared = models.NewEntityCode(destValue).Value
+		}
+		if ok && fmt.Sprint(sourceValue) == destCompared {
+			continue
+		}
+
+		result.Mismatches = append(result.Mismatches, models.FieldMismatch{
+			Field:       pair.Source,
+			DestField:   pair.Dest,
+			SourceValue: sourceValue,
+			DestValue:   destValue,
+		})
+	}
+
+	return result
+}
+
+// lookupPath resolves a dotted field path such as "event.eventName" in a decoded document.
+// Where a path segment is an array, the element whose mappingId is eventID is followed.
+func lookupPath(doc bson.M, path string, eventID string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, key := range strings.Split(path, ".") {
+		if array, isArray := current.(bson.A); isArray {
+			current = matchingElement(array, eventID)
+		}
+		nested, ok := current.(bson.M)
+		if !ok {
+			return nil, false
+		}
+		current, ok = nested[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// matchingElement returns the array element whose mappingId is eventID, or nil if there is none
+func matchingElement(array bson.A, eventID string) interface{} {
+	for _, element := range array {
+		if nested, ok := element.(bson.M); ok && fmt.Sprint(nested["mappingId"]) == eventID {
+			return nested
+		}
+	}
+	return nil
+}
+
 // Check if an error is timeout related
 func isTimeoutError(err error) bool {
 	errMsg := err.Error()
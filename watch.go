@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"analytics/config"
+	"analytics/db"
+	"analytics/models"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// runWatch validates each newly inserted recovery document once it has settled. Every
+// document is a run of its own, so its findings reach the sinks as soon as it is checked.
+func runWatch(database *mongo.Database, cfg *config.Configuration) {
+	// Re-checks would hold up the change stream for their whole delay, the settle delay serves the same purpose
+	if len(cfg.RecheckDelays) > 0 {
+		fmt.Println("⚠️ -recheck-delays is ignored by the watch command, use -settle-delay instead")
+		watchCfg := *cfg
+		watchCfg.RecheckDelays = nil
+		cfg = &watchCfg
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	resumeToken, err := db.LoadResumeToken(database, cfg.ResumeCollection, cfg.CollectionName, cfg.QueryTimeout)
+	if err != nil {
+		log.Fatalf("Failed to load resume token: %v", err)
+	}
+
+	stream, err := db.WatchEventRecoveries(ctx, database, cfg.CollectionName, resumeToken)
+	if err != nil {
+		log.Fatalf("Failed to open change stream on %s: %v", cfg.CollectionName, err)
+	}
+	defer stream.Close(context.Background())
+
+	if resumeToken != nil {
+		fmt.Printf("Watching %s, resuming from the stored position\n", cfg.CollectionName)
+	} else {
+		fmt.Printf("Watching %s for new documents\n", cfg.CollectionName)
+	}
+
+	var processed int
+	for stream.Next(ctx) {
+		var change db.RecoveryInsert
+		if err := stream.Decode(&change); err != nil {
+			fmt.Printf("⚠️ Skipping undecodable change event: %v\n", err)
+			continue
+		}
+
+		// Give the pipeline time to write the recovered events to their destinations
+		if wait := time.Until(change.InsertedAt().Add(cfg.SettleDelay)); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				// The token is not saved, so this document is validated after a restart
+				fmt.Printf("Stopped watching after %d documents\n", processed)
+				return
+			}
+		}
+
+		// Destination failures are reported as errors of this document's run, the recheck command picks them up
+		fmt.Printf("Validating document %s inserted at %s\n", change.FullDocument.ID.Hex(), change.InsertedAt().Format(time.RFC3339))
+		validateRecoveries(database, cfg, []models.EventRecovery{change.FullDocument}, time.Now(), time.Time{})
+		processed++
+
+		// Only advance past documents that were validated
+		if err := db.SaveResumeToken(database, cfg.ResumeCollection, cfg.CollectionName, stream.ResumeToken(), cfg.QueryTimeout); err != nil {
+			fmt.Printf("⚠️ Failed to save resume token: %v\n", err)
+		}
+	}
+
+	if err := stream.Err(); err != nil && ctx.Err() == nil {
+		log.Fatalf("Change stream failed: %v", err)
+	}
+	fmt.Printf("Stopped watching after %d documents\n", processed)
+}